@@ -0,0 +1,152 @@
+package DBDriver
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type Aggregate struct {
+	// Func is one of count, count_distinct, sum, avg, min and max.
+	Func   string
+	Column string
+	As     string
+}
+
+func checkColumn(column string) error {
+	if !columnRegexp.MatchString(column) {
+		return errors.New("DBDriver: invalid column " + column)
+	}
+	return nil
+}
+
+func (a Aggregate) expr() (string, error) {
+	column := a.Column
+	if column == "" || column == "*" {
+		column = "1"
+	} else if err := checkColumn(column); err != nil {
+		return "", err
+	}
+	as := a.As
+	if as == "" {
+		as = strings.ToLower(a.Func) + "_" + strings.ReplaceAll(a.Column, ".", "_")
+	}
+	if err := checkColumn(as); err != nil {
+		return "", err
+	}
+	switch strings.ToLower(a.Func) {
+	case "count", "sum", "avg", "min", "max":
+		return strings.ToLower(a.Func) + "(" + column + ") as " + as, nil
+	case "count_distinct":
+		return "count(distinct " + column + ") as " + as, nil
+	}
+	return "", errors.New("DBDriver: unsupported aggregate " + a.Func)
+}
+
+// queryScalar scans the single value returned by s into dest; no row is ErrNotFound.
+func queryScalar(query func(string, ...interface{}) (*sql.Rows, error), s string, dest interface{}) error {
+	rows, err := query(s)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return ClassifyError(sql.ErrNoRows)
+	}
+	return rows.Scan(dest)
+}
+
+func aggregateFloat(query func(string, ...interface{}) (*sql.Rows, error), from, fn, column string, where string) (float64, error) {
+	if err := checkColumn(column); err != nil {
+		return 0, err
+	}
+	var value sql.NullFloat64
+	err := queryScalar(query, "select "+fn+"("+column+") from "+from+where, &value)
+	if err != nil {
+		return 0, err
+	}
+	return value.Float64, nil
+}
+
+func aggregateValue(query func(string, ...interface{}) (*sql.Rows, error), from, fn, column string, where string) (interface{}, error) {
+	if err := checkColumn(column); err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err := queryScalar(query, "select "+fn+"("+column+") from "+from+where, &value)
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := value.([]uint8); ok {
+		value = string(b)
+	}
+	return value, nil
+}
+
+func countDistinct(query func(string, ...interface{}) (*sql.Rows, error), from, column string, where string) (int64, error) {
+	if err := checkColumn(column); err != nil {
+		return 0, err
+	}
+	var count int64
+	err := queryScalar(query, "select count(distinct "+column+") from "+from+where, &count)
+	return count, err
+}
+
+func pluck(query func(string, ...interface{}) (*sql.Rows, error), from, column string, where string, orderBy string) ([]interface{}, error) {
+	if err := checkColumn(column); err != nil {
+		return nil, err
+	}
+	s := "select " + column + " from " + from + where
+	if CheckOrderBy(orderBy) {
+		s += " order by " + orderBy
+	}
+	rows, err := query(s)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make([]interface{}, 0, 10)
+	for rows.Next() {
+		var value interface{}
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		if b, ok := value.([]uint8); ok {
+			value = string(b)
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func groupBy(query func(string, ...interface{}) (*sql.Rows, error), from string, columns []string, aggregates []Aggregate, where string) ([]map[string]interface{}, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("DBDriver: group by needs at least one column")
+	}
+	for _, column := range columns {
+		if err := checkColumn(column); err != nil {
+			return nil, err
+		}
+	}
+	if len(aggregates) == 0 {
+		aggregates = []Aggregate{{Func: "count", As: "count"}}
+	}
+	group := strings.Join(columns, ", ")
+	s := "select " + group
+	for _, a := range aggregates {
+		expr, err := a.expr()
+		if err != nil {
+			return nil, err
+		}
+		s += ", " + expr
+	}
+	s += " from " + from + where + " group by " + group + " order by " + group
+	rows, err := query(s)
+	if err != nil {
+		return nil, err
+	}
+	return ReturnListFromResults(rows)
+}
@@ -0,0 +1,86 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const DefaultBatchSize int64 = 1000
+
+type BatchOptions struct {
+	// Size is the maximum number of rows touched by one statement.
+	Size int64
+	// Sleep is the pause between two batches, giving replicas and other writers room.
+	Sleep time.Duration
+	// Progress is called after every batch with its affected rows and the running total.
+	Progress func(affected, total int64)
+}
+
+// batchLoop calls step with the batch size and the rows affected so far until it is
+// done, or until ctx is cancelled while sleeping between batches. The driver's
+// MaxAffected limit applies to that running total: the batch that would take it past
+// the limit is rolled back with ErrTooManyRows, earlier batches stay committed.
+func batchLoop(ctx context.Context, options *BatchOptions, step func(size, total int64) (int64, bool, error)) (int64, error) {
+	if options == nil {
+		options = &BatchOptions{}
+	}
+	size := options.Size
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	var total int64
+	for {
+		affected, done, err := step(size, total)
+		if err != nil {
+			return total, err
+		}
+		total += affected
+		if options.Progress != nil {
+			options.Progress(affected, total)
+		}
+		if done {
+			return total, nil
+		}
+		if options.Sleep > 0 {
+			timer := time.NewTimer(options.Sleep)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return total, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// batchUpdateById updates matching rows by id ranges so that rows which still match
+// the filter after being updated are not visited again. query must read from the
+// primary, as a lagging replica would miss rows. The ids are read without a lock,
+// so the update repeats the filter and skips rows that stopped matching since.
+func batchUpdateById(ctx context.Context, query func(string, ...interface{}) (*sql.Rows, error), exec func(s string, total int64, args ...interface{}) (int64, error), driverName, from, where, update string, options *BatchOptions) (int64, error) {
+	var lastId interface{}
+	return batchLoop(ctx, options, func(size, total int64) (int64, bool, error) {
+		cond := where
+		var args []interface{}
+		if lastId != nil {
+			cond = appendWhere(where, "id > "+placeholder(driverName, 1))
+			args = append(args, lastId)
+		}
+		rows, err := query("select id from "+from+cond+" order by id asc limit "+SqlQuote(size), args...)
+		if err != nil {
+			return 0, true, err
+		}
+		ids := make([]interface{}, 0, size)
+		err = Each(rows, func(row Row) error {
+			ids = append(ids, row["id"])
+			return nil
+		})
+		if err != nil || len(ids) == 0 {
+			return 0, true, err
+		}
+		lastId = ids[len(ids)-1]
+		affected, err := exec(update+appendWhere(where, "id in "+placeholderList(driverName, len(ids))), total, ids...)
+		return affected, int64(len(ids)) < size, err
+	})
+}
@@ -0,0 +1,290 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrCircuitOpen    = errors.New("DBDriver: circuit breaker is open")
+	ErrLimiterFull    = errors.New("DBDriver: too many queued statements")
+	ErrLimiterTimeout = errors.New("DBDriver: timed out waiting for a statement slot")
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// StateMetrics is implemented by Metrics backends that also track breaker and limiter
+// state. name is the driver's primary pool name, see Metrics.
+type StateMetrics interface {
+	SetBreakerState(name string, state BreakerState)
+	SetLimiterState(name string, inFlight, queued int64)
+}
+
+// CircuitBreaker fails statements fast with ErrCircuitOpen after FailureThreshold
+// consecutive failures. After OpenTimeout up to HalfOpenProbes statements are let
+// through; a success closes the breaker again, a failure reopens it.
+type CircuitBreaker struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	HalfOpenProbes   int
+	// IsFailure defaults to connection errors and deadline exceeded.
+	IsFailure     func(error) bool
+	OnStateChange func(from, to BreakerState)
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{FailureThreshold: failureThreshold, OpenTimeout: openTimeout, HalfOpenProbes: 1}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	b.failures = 0
+	b.probes = 0
+	if state == BreakerOpen {
+		b.openedAt = time.Now()
+	}
+	if b.OnStateChange != nil {
+		go b.OnStateChange(from, state)
+	}
+}
+
+func (b *CircuitBreaker) isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if b.IsFailure != nil {
+		return b.IsFailure(err)
+	}
+	return errors.Is(err, ErrConnection) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// allow reports whether a statement may run; every allowed statement must be
+// followed by done. A nil breaker allows everything.
+func (b *CircuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen {
+		if time.Since(b.openedAt) < b.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.setState(BreakerHalfOpen)
+	}
+	if b.state == BreakerHalfOpen {
+		probes := b.HalfOpenProbes
+		if probes <= 0 {
+			probes = 1
+		}
+		if b.probes >= probes {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) done(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	failed := b.isFailure(err)
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed {
+			b.setState(BreakerOpen)
+		} else {
+			b.setState(BreakerClosed)
+		}
+	case BreakerClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.FailureThreshold > 0 && b.failures >= b.FailureThreshold {
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+// Limiter bounds the statements a driver starts at once. Callers beyond MaxConcurrent
+// wait in a queue of at most MaxQueue for up to QueueTimeout, then fail fast.
+// A query holds its slot until it returns its rows, not until they are closed;
+// a transaction holds one slot from begin to commit or rollback.
+type Limiter struct {
+	MaxConcurrent int
+	MaxQueue      int
+	QueueTimeout  time.Duration
+	OnChange      func(inFlight, queued int64)
+
+	once     sync.Once
+	slots    chan struct{}
+	inFlight int64
+	queued   int64
+}
+
+func NewLimiter(maxConcurrent, maxQueue int, queueTimeout time.Duration) *Limiter {
+	return &Limiter{MaxConcurrent: maxConcurrent, MaxQueue: maxQueue, QueueTimeout: queueTimeout}
+}
+
+func (l *Limiter) InFlight() int64 {
+	return atomic.LoadInt64(&l.inFlight)
+}
+
+func (l *Limiter) Queued() int64 {
+	return atomic.LoadInt64(&l.queued)
+}
+
+func (l *Limiter) changed() {
+	if l.OnChange != nil {
+		l.OnChange(l.InFlight(), l.Queued())
+	}
+}
+
+// limits reports whether l bounds anything; a nil Limiter does not.
+func (l *Limiter) limits() bool {
+	return l != nil && l.MaxConcurrent > 0
+}
+
+// acquire waits for a slot; every successful acquire must be followed by release.
+func (l *Limiter) acquire(ctx context.Context) error {
+	if !l.limits() {
+		return nil
+	}
+	l.once.Do(func() {
+		l.slots = make(chan struct{}, l.MaxConcurrent)
+	})
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.inFlight, 1)
+		l.changed()
+		return nil
+	default:
+	}
+	if atomic.AddInt64(&l.queued, 1) > int64(l.MaxQueue) {
+		atomic.AddInt64(&l.queued, -1)
+		return ErrLimiterFull
+	}
+	defer atomic.AddInt64(&l.queued, -1)
+	var timeout <-chan time.Time
+	if l.QueueTimeout > 0 {
+		timer := time.NewTimer(l.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	l.changed()
+	select {
+	case l.slots <- struct{}{}:
+		atomic.AddInt64(&l.inFlight, 1)
+		l.changed()
+		return nil
+	case <-timeout:
+		return ErrLimiterTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) release() {
+	if !l.limits() {
+		return
+	}
+	<-l.slots
+	atomic.AddInt64(&l.inFlight, -1)
+	l.changed()
+}
+
+// guardedQuery runs query on conn behind limiter and breaker. The limiter slot is
+// given back once the query has started, so reading the rows takes none.
+func guardedQuery(ctx context.Context, breaker *CircuitBreaker, limiter *Limiter, conn sqlConn, query string, args []interface{}) (*sql.Rows, error) {
+	if _, inTx := conn.(*sql.Tx); inTx {
+		limiter = nil
+	}
+	if err := limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer limiter.release()
+	if err := breaker.allow(); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args...)
+	breaker.done(ClassifyError(err))
+	return rows, err
+}
+
+// guardedExec runs query on conn behind limiter and breaker.
+func guardedExec(ctx context.Context, breaker *CircuitBreaker, limiter *Limiter, conn sqlConn, query string, args []interface{}) (sql.Result, error) {
+	if _, inTx := conn.(*sql.Tx); inTx {
+		limiter = nil
+	}
+	if err := limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer limiter.release()
+	if err := breaker.allow(); err != nil {
+		return nil, err
+	}
+	result, err := conn.ExecContext(ctx, query, args...)
+	breaker.done(ClassifyError(err))
+	return result, err
+}
+
+// beginTx begins a transaction on pool behind limiter and breaker. The transaction
+// holds one limiter slot until release is called, so its statements take none.
+func beginTx(ctx context.Context, breaker *CircuitBreaker, limiter *Limiter, pool *sql.DB) (tx *sql.Tx, release func(), err error) {
+	if err = limiter.acquire(ctx); err != nil {
+		return nil, nil, err
+	}
+	if err = breaker.allow(); err != nil {
+		limiter.release()
+		return nil, nil, err
+	}
+	tx, err = pool.BeginTx(ctx, nil)
+	breaker.done(ClassifyError(err))
+	if err != nil {
+		limiter.release()
+		return nil, nil, err
+	}
+	var once sync.Once
+	return tx, func() { once.Do(limiter.release) }, nil
+}
@@ -0,0 +1,103 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lichv/go-dbDriver/DBDriver/internal/stubdb"
+)
+
+func newStubDriver(t *testing.T) *MysqlDriver {
+	return &MysqlDriver{DriverName: "mysql", DB: stubdb.Open(t)}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(2, time.Hour)
+	failure := &DBError{Kind: ErrConnection, Err: errors.New("refused")}
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("allow %d: %v", i, err)
+		}
+		b.done(failure)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow on an open breaker = %v, want ErrCircuitOpen", err)
+	}
+
+	b.OpenTimeout = 0
+	if err := b.allow(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second probe = %v, want ErrCircuitOpen", err)
+	}
+	b.done(nil)
+	if b.State() != BreakerClosed {
+		t.Errorf("state after a good probe = %v, want closed", b.State())
+	}
+}
+
+func TestLimiterReleasesSlotOnceQueryStarts(t *testing.T) {
+	db := newStubDriver(t)
+	limiter := NewLimiter(1, 0, 0)
+	db.SetLimiter(limiter)
+
+	rows, err := db.QueryContext(context.Background(), "select id from article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if n := limiter.InFlight(); n != 0 {
+		t.Errorf("in flight with rows open = %d, want 0", n)
+	}
+	if _, err = db.ExecContext(context.Background(), "delete from article where id = 1"); err != nil {
+		t.Errorf("exec with rows open: %v", err)
+	}
+}
+
+func TestLimiterGetPage(t *testing.T) {
+	db := newStubDriver(t)
+	db.SetLimiter(NewLimiter(1, 0, 0))
+	for i := 0; i < 100; i++ {
+		rows, _, err := db.GetPageContext(context.Background(), "article", nil, "id", 1, 10)
+		if err != nil {
+			t.Fatalf("page %d: %v", i, err)
+		}
+		rows.Close()
+	}
+}
+
+func TestSettersReplaceAndRemove(t *testing.T) {
+	db := newStubDriver(t)
+	breaker := NewCircuitBreaker(1, time.Hour)
+	db.SetCircuitBreaker(breaker)
+	db.SetCircuitBreaker(breaker)
+	db.SetLimiter(NewLimiter(1, 0, 0))
+	db.SetMetrics(&ExpvarMetrics{
+		queries:  map[string]*expvarHistogram{},
+		pools:    map[string]func() sql.DBStats{},
+		breakers: map[string]string{},
+		limiters: map[string][2]int64{},
+	})
+	if len(db.Hooks) != 0 {
+		t.Errorf("setters added %d hooks", len(db.Hooks))
+	}
+
+	breaker.allow()
+	breaker.done(&DBError{Kind: ErrConnection})
+	if _, err := db.ExecContext(context.Background(), "delete from article"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("exec with an open breaker = %v, want ErrCircuitOpen", err)
+	}
+	db.SetCircuitBreaker(nil)
+	db.SetLimiter(nil)
+	db.SetMetrics(nil)
+	if _, err := db.ExecContext(context.Background(), "delete from article"); err != nil {
+		t.Errorf("exec after removing the breaker: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package DBDriver
+
+import (
+	"database/sql"
+	"errors"
+)
+
+const DefaultCursorBatch int64 = 1000
+
+func appendWhere(where string, condition string) string {
+	if where == "" {
+		return " where " + condition
+	}
+	return where + " and " + condition
+}
+
+// walkById reads from in batches of size ordered by id, using id > lastId
+// instead of offsets so that each batch is an index range scan.
+func walkById(query func(string, ...interface{}) (*sql.Rows, error), driverName string, from string, where string, size int64, fn func([]Row) error) error {
+	if size <= 0 {
+		size = DefaultCursorBatch
+	}
+	var lastId interface{}
+	for {
+		cond := where
+		var args []interface{}
+		if lastId != nil {
+			cond = appendWhere(where, "id > "+placeholder(driverName, 1))
+			args = append(args, lastId)
+		}
+		rows, err := query("select * from "+from+cond+" order by id asc limit "+SqlQuote(size), args...)
+		if err != nil {
+			return err
+		}
+		batch := make([]Row, 0, size)
+		err = Each(rows, func(row Row) error {
+			batch = append(batch, row)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err = fn(batch); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+		if int64(len(batch)) < size {
+			return nil
+		}
+		lastId = batch[len(batch)-1]["id"]
+		if lastId == nil {
+			return errors.New("DBDriver: rows have no id column")
+		}
+	}
+}
+
+func eachInBatch(fn func(Row) error) func([]Row) error {
+	return func(batch []Row) error {
+		for _, row := range batch {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,135 @@
+// Package dbotel traces DBDriver statements with OpenTelemetry. It lives in its own
+// package so that the driver itself does not depend on OpenTelemetry.
+//
+//	db := DBDriver.InitPostgreDriver("localhost", 5432, "adminb", "123456", "data")
+//	_ = dbotel.Instrument(db)
+//	rows, err := db.QueryContext(ctx, "select * from article where id = $1", 1)
+//	id, err := db.InsertContext(ctx, "article", post)
+//
+// Spans become children of the span in the context passed to QueryContext,
+// ExecContext and the XContext variants of the map based methods; the plain methods
+// start root spans. Tests can pass a TracerProvider backed by
+// tracetest.NewInMemoryExporter.
+package dbotel
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/lichv/go-dbDriver/DBDriver"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/lichv/go-dbDriver/DBDriver/dbotel"
+
+type config struct {
+	provider  trace.TracerProvider
+	statement func(string) string
+	attrs     []attribute.KeyValue
+}
+
+type Option func(*config)
+
+// WithTracerProvider uses provider instead of the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithStatement replaces the default sanitiser for db.statement, DBDriver.Fingerprint,
+// which strips literal values. Pass nil to leave db.statement out.
+func WithStatement(sanitize func(string) string) Option {
+	return func(c *config) {
+		c.statement = sanitize
+	}
+}
+
+// WithAttributes adds attrs to every span, e.g. db.name or server.address.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+type hook struct {
+	tracer    trace.Tracer
+	system    string
+	statement func(string) string
+	attrs     []attribute.KeyValue
+}
+
+// NewHook returns a DBDriver.Hook creating one client span per statement.
+// system is the db.system value, e.g. mysql or postgresql.
+func NewHook(system string, options ...Option) DBDriver.Hook {
+	c := &config{provider: otel.GetTracerProvider(), statement: DBDriver.Fingerprint}
+	for _, option := range options {
+		option(c)
+	}
+	return &hook{
+		tracer:    c.provider.Tracer(instrumentationName),
+		system:    system,
+		statement: c.statement,
+		attrs:     c.attrs,
+	}
+}
+
+// Instrument adds a tracing hook to a MysqlDriver or PostgresDriver.
+func Instrument(db DBDriver.ObservedDriver, options ...Option) error {
+	switch db.(type) {
+	case *DBDriver.MysqlDriver:
+		return db.AddHook(NewHook("mysql", options...))
+	case *DBDriver.PostgresDriver:
+		return db.AddHook(NewHook("postgresql", options...))
+	}
+	return errors.New("dbotel: unsupported driver")
+}
+
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+func (h *hook) Before(ctx context.Context, event *DBDriver.QueryEvent) (context.Context, error) {
+	name := event.Operation
+	if event.Table != "" {
+		name += " " + event.Table
+	}
+	attrs := make([]attribute.KeyValue, 0, len(h.attrs)+5)
+	attrs = append(attrs,
+		attribute.String("db.system", h.system),
+		attribute.String("db.operation", sqlOperation(event.SQL)),
+		attribute.String("dbdriver.method", event.Operation),
+	)
+	if event.Table != "" {
+		attrs = append(attrs, attribute.String("db.sql.table", event.Table))
+	}
+	if h.statement != nil {
+		attrs = append(attrs, attribute.String("db.statement", h.statement(event.SQL)))
+	}
+	attrs = append(attrs, h.attrs...)
+	ctx, _ = h.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, nil
+}
+
+func (h *hook) After(ctx context.Context, event *DBDriver.QueryEvent) {
+	span := trace.SpanFromContext(ctx)
+	if event.RowsAffected >= 0 {
+		span.SetAttributes(attribute.Int64("db.rows_affected", event.RowsAffected))
+	}
+	if event.InTx {
+		span.SetAttributes(attribute.Bool("db.transaction", true))
+	}
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+	span.End()
+}
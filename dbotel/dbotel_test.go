@@ -0,0 +1,101 @@
+package dbotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lichv/go-dbDriver/DBDriver"
+	"github.com/lichv/go-dbDriver/DBDriver/internal/stubdb"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestDriver(t *testing.T) *DBDriver.MysqlDriver {
+	return &DBDriver.MysqlDriver{DriverName: "mysql", DB: stubdb.Open(t)}
+}
+
+func TestInstrumentSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	db := newTestDriver(t)
+	if err := Instrument(db, WithTracerProvider(provider)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	if _, err := db.InsertContext(ctx, "article", map[string]interface{}{"title": "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.GetListContext(ctx, "article", map[string]interface{}{"id": 1}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	want := []struct {
+		name  string
+		attrs map[attribute.Key]attribute.Value
+	}{
+		{"Insert article", map[attribute.Key]attribute.Value{
+			"db.system":        attribute.StringValue("mysql"),
+			"db.operation":     attribute.StringValue("INSERT"),
+			"dbdriver.method":  attribute.StringValue("Insert"),
+			"db.sql.table":     attribute.StringValue("article"),
+			"db.statement":     attribute.StringValue("insert into `article` (title) values (?)"),
+			"db.rows_affected": attribute.Int64Value(1),
+		}},
+		{"GetList article", map[attribute.Key]attribute.Value{
+			"db.system":       attribute.StringValue("mysql"),
+			"db.operation":    attribute.StringValue("SELECT"),
+			"dbdriver.method": attribute.StringValue("GetList"),
+			"db.sql.table":    attribute.StringValue("article"),
+			"db.statement":    attribute.StringValue("select * from article where id=?"),
+		}},
+	}
+	for i, w := range want {
+		span := spans[i]
+		if span.Name != w.name {
+			t.Errorf("span %d name = %q, want %q", i, span.Name, w.name)
+		}
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %q is not a child of the caller's span", span.Name)
+		}
+		got := map[attribute.Key]attribute.Value{}
+		for _, kv := range span.Attributes {
+			got[kv.Key] = kv.Value
+		}
+		for key, value := range w.attrs {
+			if got[key] != value {
+				t.Errorf("span %q %s = %v, want %v", span.Name, key, got[key].Emit(), value.Emit())
+			}
+		}
+	}
+}
+
+func TestInstrumentNestedHooks(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	db := newTestDriver(t)
+	for i := 0; i < 2; i++ {
+		if err := Instrument(db, WithTracerProvider(provider)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.ExecContext(context.Background(), "delete from article where id = ?", 1); err != nil {
+		t.Fatal(err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d ended spans, want 2", len(spans))
+	}
+	inner, outer := spans[0], spans[1]
+	if inner.Parent.SpanID() != outer.SpanContext.SpanID() {
+		t.Errorf("inner span is not a child of the outer span")
+	}
+}
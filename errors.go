@@ -0,0 +1,157 @@
+package DBDriver
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrNotFound is the Kind of the error single row reads such as
+	// ReturnMapFromResult, CountJoin or ServerVersion return when there is no row.
+	ErrNotFound            = errors.New("DBDriver: not found")
+	ErrDuplicateKey        = errors.New("DBDriver: duplicate key")
+	ErrForeignKeyViolation = errors.New("DBDriver: foreign key violation")
+	ErrNotNullViolation    = errors.New("DBDriver: not null violation")
+	ErrCheckViolation      = errors.New("DBDriver: check violation")
+	ErrDeadlock            = errors.New("DBDriver: deadlock")
+	ErrLockTimeout         = errors.New("DBDriver: lock timeout")
+	ErrSerialization       = errors.New("DBDriver: serialization failure")
+	ErrConnection          = errors.New("DBDriver: connection error")
+)
+
+// DBError is a classified database error. errors.Is matches its Kind, errors.As
+// still reaches the driver error, e.g. *mysql.MySQLError or *pq.Error.
+type DBError struct {
+	Kind error
+	// Code is the MySQL error number or the Postgres SQLSTATE.
+	Code       string
+	Table      string
+	Column     string
+	Constraint string
+	Err        error
+}
+
+func (e *DBError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DBError) Is(target error) bool {
+	return target == e.Kind
+}
+
+var (
+	mysqlKeyRegexp        = regexp.MustCompile("for key '([^']+)'")
+	mysqlConstraintRegexp = regexp.MustCompile("CONSTRAINT `([^`]+)`")
+	mysqlColumnRegexp     = regexp.MustCompile("(?:Column|Field) '([^']+)'")
+	mysqlCheckRegexp      = regexp.MustCompile("Check constraint '([^']+)'")
+)
+
+var mysqlKinds = map[uint16]error{
+	1062: ErrDuplicateKey,
+	1586: ErrDuplicateKey,
+	1216: ErrForeignKeyViolation,
+	1217: ErrForeignKeyViolation,
+	1451: ErrForeignKeyViolation,
+	1452: ErrForeignKeyViolation,
+	1048: ErrNotNullViolation,
+	1364: ErrNotNullViolation,
+	3819: ErrCheckViolation,
+	1213: ErrDeadlock,
+	1205: ErrLockTimeout,
+	1040: ErrConnection,
+	1053: ErrConnection,
+	1927: ErrConnection,
+}
+
+var postgresKinds = map[string]error{
+	"23505": ErrDuplicateKey,
+	"23503": ErrForeignKeyViolation,
+	"23502": ErrNotNullViolation,
+	"23514": ErrCheckViolation,
+	"40P01": ErrDeadlock,
+	"55P03": ErrLockTimeout,
+	"40001": ErrSerialization,
+	"57P01": ErrConnection,
+	"57P02": ErrConnection,
+	"57P03": ErrConnection,
+}
+
+// ClassifyError wraps MySQL and Postgres errors in a *DBError with a driver
+// independent Kind. Errors it does not recognise are returned unchanged.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return &DBError{Kind: ErrNotFound, Err: err}
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		kind, ok := mysqlKinds[myErr.Number]
+		if !ok {
+			return err
+		}
+		e := &DBError{Kind: kind, Code: strconv.Itoa(int(myErr.Number)), Err: err}
+		for _, re := range []*regexp.Regexp{mysqlKeyRegexp, mysqlConstraintRegexp, mysqlCheckRegexp} {
+			if m := re.FindStringSubmatch(myErr.Message); m != nil {
+				e.Constraint = m[1]
+				break
+			}
+		}
+		if m := mysqlColumnRegexp.FindStringSubmatch(myErr.Message); m != nil {
+			e.Column = m[1]
+		}
+		return e
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := string(pqErr.Code)
+		kind, ok := postgresKinds[code]
+		if !ok && strings.HasPrefix(code, "08") {
+			kind, ok = ErrConnection, true
+		}
+		if !ok {
+			return err
+		}
+		return &DBError{Kind: kind, Code: code, Table: pqErr.Table, Column: pqErr.Column, Constraint: pqErr.Constraint, Err: err}
+	}
+
+	if isConnectionError(err) {
+		return &DBError{Kind: ErrConnection, Err: err}
+	}
+	return err
+}
+
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,102 @@
+package DBDriver
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want DBError
+	}{
+		{
+			name: "mysql duplicate key",
+			err:  &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.c' for key 'user.email'"},
+			want: DBError{Kind: ErrDuplicateKey, Code: "1062", Constraint: "user.email"},
+		},
+		{
+			name: "mysql foreign key",
+			err:  &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`shop`.`comment`, CONSTRAINT `comment_article_fk` FOREIGN KEY (`article_id`) REFERENCES `article` (`id`))"},
+			want: DBError{Kind: ErrForeignKeyViolation, Code: "1452", Constraint: "comment_article_fk"},
+		},
+		{
+			name: "mysql not null",
+			err:  &mysql.MySQLError{Number: 1048, Message: "Column 'title' cannot be null"},
+			want: DBError{Kind: ErrNotNullViolation, Code: "1048", Column: "title"},
+		},
+		{
+			name: "mysql missing default",
+			err:  &mysql.MySQLError{Number: 1364, Message: "Field 'slug' doesn't have a default value"},
+			want: DBError{Kind: ErrNotNullViolation, Code: "1364", Column: "slug"},
+		},
+		{
+			name: "mysql check",
+			err:  &mysql.MySQLError{Number: 3819, Message: "Check constraint 'price_positive' is violated."},
+			want: DBError{Kind: ErrCheckViolation, Code: "3819", Constraint: "price_positive"},
+		},
+		{
+			name: "mysql deadlock",
+			err:  &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"},
+			want: DBError{Kind: ErrDeadlock, Code: "1213"},
+		},
+		{
+			name: "mysql lock timeout",
+			err:  &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded; try restarting transaction"},
+			want: DBError{Kind: ErrLockTimeout, Code: "1205"},
+		},
+		{
+			name: "postgres unique",
+			err:  &pq.Error{Code: "23505", Table: "user", Constraint: "user_email_key"},
+			want: DBError{Kind: ErrDuplicateKey, Code: "23505", Table: "user", Constraint: "user_email_key"},
+		},
+		{
+			name: "postgres not null",
+			err:  &pq.Error{Code: "23502", Table: "article", Column: "title"},
+			want: DBError{Kind: ErrNotNullViolation, Code: "23502", Table: "article", Column: "title"},
+		},
+		{
+			name: "postgres serialization",
+			err:  &pq.Error{Code: "40001"},
+			want: DBError{Kind: ErrSerialization, Code: "40001"},
+		},
+		{
+			name: "postgres connection class",
+			err:  &pq.Error{Code: "08006"},
+			want: DBError{Kind: ErrConnection, Code: "08006"},
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("commit: %w", &pq.Error{Code: "40P01"}),
+			want: DBError{Kind: ErrDeadlock, Code: "40P01"},
+		},
+	}
+	for _, tt := range tests {
+		err := ClassifyError(tt.err)
+		var got *DBError
+		if !errors.As(err, &got) {
+			t.Errorf("%s: ClassifyError = %v, want a *DBError", tt.name, err)
+			continue
+		}
+		if !errors.Is(err, tt.want.Kind) {
+			t.Errorf("%s: kind = %v, want %v", tt.name, got.Kind, tt.want.Kind)
+		}
+		if got.Code != tt.want.Code || got.Table != tt.want.Table || got.Column != tt.want.Column || got.Constraint != tt.want.Constraint {
+			t.Errorf("%s: got code %q table %q column %q constraint %q, want %q %q %q %q", tt.name,
+				got.Code, got.Table, got.Column, got.Constraint, tt.want.Code, tt.want.Table, tt.want.Column, tt.want.Constraint)
+		}
+		if got.Err != tt.err {
+			t.Errorf("%s: wraps %v, want %v", tt.name, got.Err, tt.err)
+		}
+	}
+
+	unknown := &mysql.MySQLError{Number: 1146, Message: "Table 'shop.nope' doesn't exist"}
+	if err := ClassifyError(unknown); err != unknown {
+		t.Errorf("unknown mysql error = %v, want it unchanged", err)
+	}
+}
@@ -0,0 +1,25 @@
+package DBDriver
+
+import "strconv"
+
+// Expr is a raw SQL expression used as value in Insert, Update and filter maps.
+// It is written into the statement as is, so it must never hold user input.
+type Expr struct {
+	SQL string
+}
+
+func Raw(sql string) Expr {
+	return Expr{SQL: sql}
+}
+
+func incrementExpr(column string, by float64) (Expr, error) {
+	if err := checkColumn(column); err != nil {
+		return Expr{}, err
+	}
+	op := " + "
+	if by < 0 {
+		op = " - "
+		by = -by
+	}
+	return Raw(column + op + strconv.FormatFloat(by, 'f', -1, 64)), nil
+}
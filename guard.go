@@ -0,0 +1,100 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var (
+	ErrMissingWhere  = errors.New("DBDriver: update or delete without where clause, use UpdateAll or DeleteAll")
+	ErrInvalidFilter = errors.New("DBDriver: invalid filter")
+	ErrTooManyRows   = errors.New("DBDriver: statement affected more rows than allowed")
+)
+
+// checkWhere passes on the error of building the where clause, so a filter with a
+// condition that could not be rendered is rejected, and in safe mode rejects any
+// update or delete without a where clause.
+func checkWhere(safe bool, where string, err error) error {
+	if err != nil {
+		return err
+	}
+	if where == "" && safe {
+		return ErrMissingWhere
+	}
+	return nil
+}
+
+// execLimited runs s and returns the affected rows. When max is positive the statement
+// runs in a transaction that is rolled back if more than max rows were affected.
+func execLimited(ctx context.Context, db *sql.DB, begin txBegin, run func(context.Context, sqlConn, string, ...interface{}) (sql.Result, error), s string, max int64, args ...interface{}) (int64, error) {
+	if max <= 0 {
+		exec, err := run(ctx, db, s, args...)
+		if err != nil {
+			return 0, err
+		}
+		return exec.RowsAffected()
+	}
+	return execWithin(ctx, begin, run, s, max, args...)
+}
+
+// execBatch runs one batch of a batched update or delete after total rows were
+// already affected. With max positive the batch is rolled back when it would take the
+// total past max; the batches before it stay committed.
+func execBatch(ctx context.Context, db *sql.DB, begin txBegin, run func(context.Context, sqlConn, string, ...interface{}) (sql.Result, error), s string, max, total int64, args ...interface{}) (int64, error) {
+	if max <= 0 {
+		return execLimited(ctx, db, begin, run, s, 0, args...)
+	}
+	return execWithin(ctx, begin, run, s, max-total, args...)
+}
+
+func execWithin(ctx context.Context, begin txBegin, run func(context.Context, sqlConn, string, ...interface{}) (sql.Result, error), s string, max int64, args ...interface{}) (int64, error) {
+	tx, release, err := begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	defer tx.Rollback()
+	exec, err := run(ctx, tx, s, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := exec.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if affected > max {
+		return 0, ErrTooManyRows
+	}
+	return affected, ClassifyError(tx.Commit())
+}
+
+// queryLimited runs a statement with a returning clause and reads its rows. When max
+// is positive it runs in a transaction that is rolled back if more than max rows came back.
+func queryLimited(ctx context.Context, db *sql.DB, begin txBegin, run func(context.Context, sqlConn, string, ...interface{}) (*sql.Rows, error), s string, max int64) ([]map[string]interface{}, error) {
+	if max <= 0 {
+		rows, err := run(ctx, db, s)
+		if err != nil {
+			return nil, err
+		}
+		return ReturnListFromResults(rows)
+	}
+	tx, release, err := begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer tx.Rollback()
+	rows, err := run(ctx, tx, s)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(list)) > max {
+		return nil, ErrTooManyRows
+	}
+	return list, ClassifyError(tx.Commit())
+}
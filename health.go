@@ -0,0 +1,108 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type PoolStatus struct {
+	MaxOpen      int           `json:"max_open"`
+	Open         int           `json:"open"`
+	InUse        int           `json:"in_use"`
+	Idle         int           `json:"idle"`
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration"`
+}
+
+type HealthStatus struct {
+	Healthy       bool          `json:"healthy"`
+	Driver        string        `json:"driver"`
+	Latency       time.Duration `json:"latency"`
+	ServerVersion string        `json:"server_version,omitempty"`
+	// Role is primary or replica.
+	Role      string     `json:"role,omitempty"`
+	ReadOnly  bool       `json:"read_only"`
+	Pool      PoolStatus `json:"pool"`
+	Breaker   string     `json:"breaker,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	CheckedAt time.Time  `json:"checked_at"`
+}
+
+type HealthChecker interface {
+	Health(context.Context) *HealthStatus
+}
+
+func newHealthStatus(driverName string, stats sql.DBStats, breaker *CircuitBreaker) *HealthStatus {
+	status := &HealthStatus{
+		Driver: driverName,
+		Pool: PoolStatus{
+			MaxOpen:      stats.MaxOpenConnections,
+			Open:         stats.OpenConnections,
+			InUse:        stats.InUse,
+			Idle:         stats.Idle,
+			WaitCount:    stats.WaitCount,
+			WaitDuration: stats.WaitDuration,
+		},
+		CheckedAt: time.Now(),
+	}
+	if breaker != nil {
+		status.Breaker = breaker.State().String()
+	}
+	return status
+}
+
+// checkHealth pings db and then runs probe for version, role and read only state.
+// It talks to db directly so hooks, breaker and limiter do not hide the real state.
+func checkHealth(ctx context.Context, db *sql.DB, status *HealthStatus, probe func(context.Context, *HealthStatus) error) *HealthStatus {
+	start := time.Now()
+	err := db.PingContext(ctx)
+	status.Latency = time.Since(start)
+	if err == nil {
+		err = probe(ctx, status)
+	}
+	if err != nil {
+		status.Error = ClassifyError(err).Error()
+		return status
+	}
+	status.Healthy = true
+	return status
+}
+
+// HealthHandler serves a driver's health as JSON for liveness and readiness probes.
+// Liveness only reports that the process can answer; readiness answers 503 when the
+// database is unhealthy, or read only while RequireWritable is set.
+type HealthHandler struct {
+	Checker         HealthChecker
+	Timeout         time.Duration
+	Liveness        bool
+	RequireWritable bool
+}
+
+func NewHealthHandler(checker HealthChecker) *HealthHandler {
+	return &HealthHandler{Checker: checker, Timeout: 2 * time.Second}
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.Liveness {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"alive":true}`))
+		return
+	}
+	ctx := r.Context()
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	status := h.Checker.Health(ctx)
+	code := http.StatusOK
+	if !status.Healthy || (h.RequireWritable && status.ReadOnly) {
+		code = http.StatusServiceUnavailable
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
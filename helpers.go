@@ -1,11 +1,15 @@
 package DBDriver
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
+	"log/slog"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"time"
 )
 
@@ -45,6 +49,167 @@ type DBDriver interface {
 	ExecTX(string, ...interface{}) (sql.Result, error)
 }
 
+// The interfaces below group the optional features. They are kept out of DBDriver
+// so that other implementations and mocks of it keep compiling; MysqlDriver and
+// PostgresDriver implement all of them, reach them with a type assertion:
+//
+//	if r, ok := db.(DBDriver.ReturningDriver); ok {
+//		row, err := r.InsertReturning("article", post)
+//	}
+
+// ContextDriver and the XContext methods of the interfaces below take the caller's
+// ctx, which carries cancellation, WithLogLevel, UsePrimary and trace spans. The
+// plain methods run with context.Background().
+type ContextDriver interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	QueryMapContext(context.Context, string, map[string]interface{}) (*sql.Rows, error)
+	FindByIdContext(context.Context, string, int64) (*sql.Rows, error)
+	FindOneContext(context.Context, string, map[string]interface{}, string) (*sql.Rows, error)
+	ExistsContext(context.Context, string, map[string]interface{}) bool
+	CountContext(context.Context, string, map[string]interface{}) (int64, error)
+	GetListContext(context.Context, string, map[string]interface{}, string) (*sql.Rows, error)
+	GetPageContext(context.Context, string, map[string]interface{}, string, int64, int64) (*sql.Rows, *Page, error)
+	InsertContext(context.Context, string, map[string]interface{}) (int64, error)
+	UpdateContext(context.Context, string, map[string]interface{}, map[string]interface{}) (int64, error)
+	SaveContext(context.Context, string, map[string]interface{}) (int64, error)
+	DeleteContext(context.Context, string, map[string]interface{}) (int64, error)
+	DeleteByIdContext(context.Context, string, int64) (int64, error)
+}
+
+type ReturningDriver interface {
+	InsertReturning(string, map[string]interface{}) (map[string]interface{}, error)
+	InsertReturningContext(context.Context, string, map[string]interface{}) (map[string]interface{}, error)
+	UpdateReturning(string, map[string]interface{}, map[string]interface{}) ([]map[string]interface{}, error)
+	UpdateReturningContext(context.Context, string, map[string]interface{}, map[string]interface{}) ([]map[string]interface{}, error)
+	DeleteReturning(string, map[string]interface{}) ([]map[string]interface{}, error)
+	DeleteReturningContext(context.Context, string, map[string]interface{}) ([]map[string]interface{}, error)
+}
+
+type PagingDriver interface {
+	Paginate(string, map[string]interface{}, string, int64, int64, *PaginateOptions) (*PageResult, error)
+	PaginateContext(context.Context, string, map[string]interface{}, string, int64, int64, *PaginateOptions) (*PageResult, error)
+	GetKeysetPage(string, map[string]interface{}, string, string, int64) ([]map[string]interface{}, *KeysetPage, error)
+	GetKeysetPageContext(context.Context, string, map[string]interface{}, string, string, int64) ([]map[string]interface{}, *KeysetPage, error)
+}
+
+type BatchDriver interface {
+	Cursor(string, map[string]interface{}, string, int64, func(Row) error) error
+	CursorContext(context.Context, string, map[string]interface{}, string, int64, func(Row) error) error
+	ChunkById(string, map[string]interface{}, int64, func([]Row) error) error
+	ChunkByIdContext(context.Context, string, map[string]interface{}, int64, func([]Row) error) error
+	BatchUpdate(string, map[string]interface{}, map[string]interface{}, *BatchOptions) (int64, error)
+	BatchUpdateContext(context.Context, string, map[string]interface{}, map[string]interface{}, *BatchOptions) (int64, error)
+	BatchDelete(string, map[string]interface{}, *BatchOptions) (int64, error)
+	BatchDeleteContext(context.Context, string, map[string]interface{}, *BatchOptions) (int64, error)
+}
+
+type GuardedDriver interface {
+	UpdateAll(string, map[string]interface{}) (int64, error)
+	UpdateAllContext(context.Context, string, map[string]interface{}) (int64, error)
+	DeleteAll(string) (int64, error)
+	DeleteAllContext(context.Context, string) (int64, error)
+	SetSafe(bool) error
+	SetMaxAffected(int64) error
+}
+
+type AggregateDriver interface {
+	Sum(string, string, map[string]interface{}) (float64, error)
+	SumContext(context.Context, string, string, map[string]interface{}) (float64, error)
+	Avg(string, string, map[string]interface{}) (float64, error)
+	AvgContext(context.Context, string, string, map[string]interface{}) (float64, error)
+	Min(string, string, map[string]interface{}) (interface{}, error)
+	MinContext(context.Context, string, string, map[string]interface{}) (interface{}, error)
+	Max(string, string, map[string]interface{}) (interface{}, error)
+	MaxContext(context.Context, string, string, map[string]interface{}) (interface{}, error)
+	CountDistinct(string, string, map[string]interface{}) (int64, error)
+	CountDistinctContext(context.Context, string, string, map[string]interface{}) (int64, error)
+	Pluck(string, string, map[string]interface{}, string) ([]interface{}, error)
+	PluckContext(context.Context, string, string, map[string]interface{}, string) ([]interface{}, error)
+	GroupBy(string, []string, []Aggregate, map[string]interface{}) ([]map[string]interface{}, error)
+	GroupByContext(context.Context, string, []string, []Aggregate, map[string]interface{}) ([]map[string]interface{}, error)
+	GroupCount(string, string, map[string]interface{}) ([]map[string]interface{}, error)
+	GroupCountContext(context.Context, string, string, map[string]interface{}) ([]map[string]interface{}, error)
+}
+
+type JoinDriver interface {
+	FindOneJoin(*Select, map[string]interface{}, string) (*sql.Rows, error)
+	FindOneJoinContext(context.Context, *Select, map[string]interface{}, string) (*sql.Rows, error)
+	GetListJoin(*Select, map[string]interface{}, string) (*sql.Rows, error)
+	GetListJoinContext(context.Context, *Select, map[string]interface{}, string) (*sql.Rows, error)
+	GetPageJoin(*Select, map[string]interface{}, string, int64, int64) (*sql.Rows, *Page, error)
+	GetPageJoinContext(context.Context, *Select, map[string]interface{}, string, int64, int64) (*sql.Rows, *Page, error)
+	CountJoin(*Select, map[string]interface{}) (int64, error)
+	CountJoinContext(context.Context, *Select, map[string]interface{}) (int64, error)
+}
+
+type PreloadDriver interface {
+	Relate(string, Relation) error
+	GetListPreload(string, map[string]interface{}, string, ...string) ([]map[string]interface{}, error)
+	GetListPreloadContext(context.Context, string, map[string]interface{}, string, ...string) ([]map[string]interface{}, error)
+	GetPagePreload(string, map[string]interface{}, string, int64, int64, ...string) ([]map[string]interface{}, *Page, error)
+	GetPagePreloadContext(context.Context, string, map[string]interface{}, string, int64, int64, ...string) ([]map[string]interface{}, *Page, error)
+}
+
+type CounterDriver interface {
+	Increment(string, string, float64, map[string]interface{}) (int64, error)
+	IncrementContext(context.Context, string, string, float64, map[string]interface{}) (int64, error)
+	Decrement(string, string, float64, map[string]interface{}) (int64, error)
+	DecrementContext(context.Context, string, string, float64, map[string]interface{}) (int64, error)
+}
+
+type NamedDriver interface {
+	QueryNamed(string, interface{}) (*sql.Rows, error)
+	QueryNamedContext(context.Context, string, interface{}) (*sql.Rows, error)
+	ExecNamed(string, interface{}) (sql.Result, error)
+	ExecNamedContext(context.Context, string, interface{}) (sql.Result, error)
+	SetRebind(bool) error
+}
+
+type ObservedDriver interface {
+	SetLogger(*slog.Logger, slog.Level) error
+	SetSlowLog(*SlowLog) error
+	SlowQueries(int) []QueryStats
+	AddHook(Hook) error
+	SetMetrics(Metrics) error
+	Stats() sql.DBStats
+}
+
+type ResilientDriver interface {
+	SetRetryPolicy(*RetryPolicy) error
+	RunInTx(context.Context, func(*Tx) error) error
+	SetCircuitBreaker(*CircuitBreaker) error
+	SetLimiter(*Limiter) error
+}
+
+type ReplicaDriver interface {
+	AddReplica(string, int, string, string, string) error
+	SetBalancer(Balancer) error
+}
+
+var (
+	_ fullDriver = (*MysqlDriver)(nil)
+	_ fullDriver = (*PostgresDriver)(nil)
+)
+
+type fullDriver interface {
+	DBDriver
+	ContextDriver
+	ReturningDriver
+	PagingDriver
+	BatchDriver
+	GuardedDriver
+	AggregateDriver
+	JoinDriver
+	PreloadDriver
+	CounterDriver
+	NamedDriver
+	ObservedDriver
+	ResilientDriver
+	ReplicaDriver
+	HealthChecker
+}
+
 func CreateDBDriver(driverName string, host string, port int, user, password, dbname string) DBDriver {
 	var dbDriver DBDriver
 	if driverName == "mysql" {
@@ -59,7 +224,7 @@ func CreateDBDriver(driverName string, host string, port int, user, password, db
 }
 
 func CheckOrderBy(orderBy string) bool {
-	compile := regexp.MustCompile("(?i)^([a-zA-Z]+? +?(desc|asc) *?)(,[a-zA-Z]+? +?(asc|desc) *?)*?$")
+	compile := regexp.MustCompile("(?i)^([a-zA-Z_][a-zA-Z0-9_.]*? +?(desc|asc) *?)(, *[a-zA-Z_][a-zA-Z0-9_.]*? +?(asc|desc) *?)*?$")
 	find := compile.FindStringIndex(orderBy)
 	if find != nil {
 		return true
@@ -67,72 +232,48 @@ func CheckOrderBy(orderBy string) bool {
 	return false
 }
 
+// WhereFromQuery builds the where clause for query with the values quoted inline.
+// A key it cannot render turns the clause into " where 1 = 0" and returns an error
+// wrapping ErrInvalidFilter, so a dropped condition never widens a statement.
 func WhereFromQuery(query map[string]interface{}) (string, error) {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	s := ""
 	split := " where "
-	for k, v := range query {
-		if IsSimpleType(v) {
-			s += split + " " + k + "=" + SqlQuote(v)
-			split = " and "
-		} else if reflect.TypeOf(v).Kind() == reflect.Map {
-			m, ok := v.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			operater, ok := m["operater"]
-			if !ok {
-				continue
-			}
-			switch operater {
-			case "=":
-			case "!=":
-			case ">":
-			case ">=":
-			case "<":
-			case "<=":
-				value, ok := m["value"]
-				if !ok {
-					continue
-				}
-				o, ok := operater.(string)
-				if ok {
-					v, ok := value.(string)
-					if ok {
-						s += split + " " + k + " " + o + " " + SqlQuote(v)
-						split = " and "
-					}
-				}
-			case "like":
-				value, ok := m["value"]
-				if !ok {
-					continue
-				}
-				o, ok := operater.(string)
-				if ok {
-					v, ok := value.(string)
-					if ok {
-						s += split + " " + k + " " + o + " " + SqlQuote("%"+v+"%")
-						split = " and "
-					}
-				}
-			case "between":
-				if reflect.TypeOf(v).Kind() == reflect.Slice {
-					va, ok := v.([]interface{})
-					if ok && len(va) == 2 {
-						s += split + " " + k + " between " + SqlQuote(va[0]) + " and " + SqlQuote(va[1])
-						split = " and "
-					}
-				}
-			}
+	for _, k := range keys {
+		c, err := whereCondition(k, query[k])
+		if err != nil {
+			// callers often drop the error, so fail closed rather than widen the filter
+			return " where 1 = 0", err
 		}
+		s += split + " " + c
+		split = " and "
 	}
 	return s, nil
 }
+
+func whereCondition(k string, v interface{}) (string, error) {
+	switch v.(type) {
+	case *SubQuery, ExistsCondition, Expr, map[string]interface{}:
+		return (&WhereBuilder{Inline: true}).condition(k, v)
+	}
+	if IsSimpleType(v) {
+		return k + "=" + SqlQuote(v), nil
+	}
+	return "", fmt.Errorf("%w: unsupported value %T for %s", ErrInvalidFilter, v, k)
+}
 func GetInsertSql(tableName string, post map[string]interface{},driverName string) (string, error) {
 	s, columns, values := "", "", ""
 	split := ""
 	for k, v := range post {
-		if IsSimpleType(v) {
+		if e, ok := v.(Expr); ok {
+			columns += split + k
+			values += split + e.SQL
+			split = ", "
+		} else if IsSimpleType(v) {
 			columns += split + k
 			values += split + SqlQuote(v)
 			split = ", "
@@ -156,12 +297,18 @@ func GetUpdateSQL(tableName string, post map[string]interface{}, query map[strin
 		split = "update \"" + tableName + "\" set "
 	}
 	for k, v := range post {
-		if IsSimpleType(v) {
+		if e, ok := v.(Expr); ok {
+			s += split + " " + k + "=" + e.SQL
+			split = ", "
+		} else if IsSimpleType(v) {
 			s += split + " " + k + "=" + SqlQuote(v)
 			split = ", "
 		}
 	}
-	where, _ := WhereFromQuery(query)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return "", err
+	}
 	return s + where, nil
 }
 func ReturnMapFromResult(rows *sql.Rows) (map[string]interface{}, error) {
@@ -191,6 +338,9 @@ func ReturnMapFromResult(rows *sql.Rows) (map[string]interface{}, error) {
 	if err = rows.Err(); err != nil {
 		return map[string]interface{}{}, err
 	}
+	if len(rowsMap) == 0 {
+		return map[string]interface{}{}, ClassifyError(sql.ErrNoRows)
+	}
 	return rowsMap[0], nil
 }
 func ReturnListFromResults(rows *sql.Rows) ([]map[string]interface{}, error) {
@@ -222,6 +372,34 @@ func ReturnListFromResults(rows *sql.Rows) ([]map[string]interface{}, error) {
 	}
 	return rowsMap, nil
 }
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case uint64:
+		return int64(n), nil
+	case float64:
+		return int64(n), nil
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	case nil:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("DBDriver: cannot convert %T to int64", v)
+}
+func SqlInList(values []interface{}) string {
+	s := ""
+	split := ""
+	for _, v := range values {
+		s += split + SqlQuote(v)
+		split = ", "
+	}
+	return "(" + s + ")"
+}
 func SqlQuote(x interface{}) string {
 	if x == nil {
 		return "''"
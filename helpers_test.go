@@ -0,0 +1,69 @@
+package DBDriver
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReadsReturnFilterErrors(t *testing.T) {
+	db := newStubDriver(t)
+	ctx := context.Background()
+	query := map[string]interface{}{"id": map[string]interface{}{"operater": "~", "value": 1}}
+	reads := map[string]func() error{
+		"GetList": func() error { _, err := db.GetListContext(ctx, "article", query, ""); return err },
+		"FindOne": func() error { _, err := db.FindOneContext(ctx, "article", query, ""); return err },
+		"Count":   func() error { _, err := db.CountContext(ctx, "article", query); return err },
+		"GetPage": func() error { _, _, err := db.GetPageContext(ctx, "article", query, "", 1, 10); return err },
+		"Sum":     func() error { _, err := db.SumContext(ctx, "article", "hits", query); return err },
+		"Pluck":   func() error { _, err := db.PluckContext(ctx, "article", "id", query, ""); return err },
+		"ChunkById": func() error {
+			return db.ChunkByIdContext(ctx, "article", query, 10, func([]Row) error { return nil })
+		},
+		"GetKeysetPage": func() error {
+			_, _, err := db.GetKeysetPageContext(ctx, "article", query, "id asc", "", 10)
+			return err
+		},
+	}
+	for name, read := range reads {
+		if err := read(); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("%s = %v, want ErrInvalidFilter", name, err)
+		}
+	}
+}
+
+func TestWhereFromQueryOperators(t *testing.T) {
+	tests := []struct {
+		query map[string]interface{}
+		want  string
+	}{
+		{map[string]interface{}{"id": map[string]interface{}{"operater": "in", "value": []interface{}{1, "a"}}}, " where  id in (1, 'a')"},
+		{map[string]interface{}{"id": map[string]interface{}{"operater": "not in", "value": []interface{}{3}}}, " where  id not in (3)"},
+		{map[string]interface{}{"status": map[string]interface{}{"operater": "<>", "value": 2}}, " where  status <> 2"},
+		{map[string]interface{}{"updated_at": map[string]interface{}{"operater": "<", "value": Raw("now()")}}, " where  updated_at < now()"},
+		{map[string]interface{}{"score": map[string]interface{}{"operater": ">=", "value": &SubQuery{Table: "rank", Column: "min_score"}}}, " where  score >= (select min_score from rank)"},
+		{map[string]interface{}{"title": map[string]interface{}{"operater": "like", "value": "go"}}, " where  title like '%go%'"},
+		{map[string]interface{}{"hits": map[string]interface{}{"operater": "between", "value": []interface{}{1, 9}}}, " where  hits between 1 and 9"},
+		{map[string]interface{}{"id": map[string]interface{}{"operater": "in", "value": &SubQuery{Table: "comment", Column: "article_id", Query: map[string]interface{}{"score": 5}}}}, " where  id in (select article_id from comment where score = 5)"},
+	}
+	for _, tt := range tests {
+		got, err := WhereFromQuery(tt.query)
+		if err != nil {
+			t.Errorf("WhereFromQuery(%v): %v", tt.query, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("WhereFromQuery(%v) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestWhereFromQueryOrder(t *testing.T) {
+	query := map[string]interface{}{"status": 1, "author_id": 7, "id": 3, "title": "go"}
+	want := " where  author_id=7 and  id=3 and  status=1 and  title='go'"
+	for i := 0; i < 20; i++ {
+		if got, _ := WhereFromQuery(query); got != want {
+			t.Fatalf("WhereFromQuery = %q, want %q", got, want)
+		}
+	}
+}
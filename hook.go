@@ -0,0 +1,100 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// QueryEvent describes one statement passing through the hooks. Before hooks may
+// rewrite SQL and Args; After hooks see the outcome.
+type QueryEvent struct {
+	// Operation is the driver method that issued the statement, e.g. Insert, GetPage or Query.
+	Operation    string
+	Table        string
+	SQL          string
+	Args         []interface{}
+	InTx         bool
+	Start        time.Time
+	Duration     time.Duration
+	RowsAffected int64
+	Err          error
+}
+
+// Hook runs around every statement issued by a driver, raw and map based alike.
+// An error from Before blocks the statement and is returned to the caller; After is
+// then called only for the hooks whose Before already ran. After receives the ctx
+// returned by the same hook's Before.
+type Hook interface {
+	Before(ctx context.Context, event *QueryEvent) (context.Context, error)
+	After(ctx context.Context, event *QueryEvent)
+}
+
+// HookFuncs adapts plain functions to Hook, either may be nil.
+type HookFuncs struct {
+	BeforeFunc func(ctx context.Context, event *QueryEvent) (context.Context, error)
+	AfterFunc  func(ctx context.Context, event *QueryEvent)
+}
+
+func (h HookFuncs) Before(ctx context.Context, event *QueryEvent) (context.Context, error) {
+	if h.BeforeFunc == nil {
+		return ctx, nil
+	}
+	return h.BeforeFunc(ctx, event)
+}
+
+func (h HookFuncs) After(ctx context.Context, event *QueryEvent) {
+	if h.AfterFunc != nil {
+		h.AfterFunc(ctx, event)
+	}
+}
+
+type operationKey struct{}
+
+type operation struct {
+	name  string
+	table string
+}
+
+// withOperation tags ctx with the driver method and table for hooks.
+func withOperation(ctx context.Context, name, table string) context.Context {
+	if _, ok := ctx.Value(operationKey{}).(operation); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, operationKey{}, operation{name: name, table: table})
+}
+
+func newQueryEvent(ctx context.Context, defaultOperation string, conn sqlConn, query string, args []interface{}) *QueryEvent {
+	op, ok := ctx.Value(operationKey{}).(operation)
+	if !ok {
+		op.name = defaultOperation
+	}
+	_, inTx := conn.(*sql.Tx)
+	return &QueryEvent{Operation: op.name, Table: op.table, SQL: query, Args: args, InTx: inTx, RowsAffected: -1}
+}
+
+// runHooks calls the Before hooks in order, then call, then the After hooks in
+// reverse order. Each After gets the ctx its own Before returned.
+func runHooks(ctx context.Context, hooks []Hook, event *QueryEvent, call func(context.Context) error) error {
+	ctxs := make([]context.Context, 0, len(hooks))
+	var err error
+	for _, hook := range hooks {
+		var next context.Context
+		next, err = hook.Before(ctx, event)
+		if err != nil {
+			event.Err = err
+			break
+		}
+		ctx = next
+		ctxs = append(ctxs, ctx)
+	}
+	if err == nil {
+		event.Start = time.Now()
+		event.Err = call(ctx)
+		event.Duration = time.Since(event.Start)
+	}
+	for i := len(ctxs) - 1; i >= 0; i-- {
+		hooks[i].After(ctxs[i], event)
+	}
+	return event.Err
+}
@@ -0,0 +1,50 @@
+package DBDriver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type hookKey struct{}
+
+func TestRunHooksContexts(t *testing.T) {
+	var order []string
+	hook := func(name string, fail bool) Hook {
+		return HookFuncs{
+			BeforeFunc: func(ctx context.Context, event *QueryEvent) (context.Context, error) {
+				if fail {
+					return ctx, errors.New("blocked by " + name)
+				}
+				return context.WithValue(ctx, hookKey{}, name), nil
+			},
+			AfterFunc: func(ctx context.Context, event *QueryEvent) {
+				order = append(order, name+":"+ctx.Value(hookKey{}).(string))
+			},
+		}
+	}
+	var called string
+	err := runHooks(context.Background(), []Hook{hook("a", false), hook("b", false)}, &QueryEvent{}, func(ctx context.Context) error {
+		called = ctx.Value(hookKey{}).(string)
+		return nil
+	})
+	if err != nil || called != "b" {
+		t.Fatalf("call saw %q, err %v", called, err)
+	}
+	if want := []string{"b:b", "a:a"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("After order = %v, want %v", order, want)
+	}
+
+	order = nil
+	err = runHooks(context.Background(), []Hook{hook("a", false), hook("b", true), hook("c", false)}, &QueryEvent{}, func(ctx context.Context) error {
+		t.Fatal("call ran after a failing Before")
+		return nil
+	})
+	if err == nil || err.Error() != "blocked by b" {
+		t.Errorf("err = %v, want blocked by b", err)
+	}
+	if want := []string{"a:a"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("After order = %v, want %v", order, want)
+	}
+}
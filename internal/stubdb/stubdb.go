@@ -0,0 +1,69 @@
+// Package stubdb provides a database/sql driver for tests that needs no server. It
+// answers every query with one row holding id 1 and every exec with one affected row.
+package stubdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+)
+
+const DriverName = "dbdriverstub"
+
+type stubDriver struct{}
+
+type stubConn struct{}
+
+type stubTx struct{}
+
+type stubRows struct{ done bool }
+
+type stubResult struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return stubTx{}, nil }
+
+func (stubConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &stubRows{}, nil
+}
+
+func (stubConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return stubResult{}, nil
+}
+
+func (stubTx) Commit() error   { return nil }
+func (stubTx) Rollback() error { return nil }
+
+func (r *stubRows) Columns() []string { return []string{"id"} }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func (stubResult) LastInsertId() (int64, error) { return 1, nil }
+func (stubResult) RowsAffected() (int64, error) { return 1, nil }
+
+func init() {
+	sql.Register(DriverName, stubDriver{})
+}
+
+// Open returns a pool on the stub driver that is closed when t finishes.
+func Open(t testing.TB) *sql.DB {
+	db, err := sql.Open(DriverName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
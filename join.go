@@ -0,0 +1,119 @@
+package DBDriver
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+type Join struct {
+	// Type is inner, left or right, inner when empty.
+	Type  string
+	Table string
+	As    string
+	// On is one or more column equalities joined with and, e.g. "u.id = a.author_id".
+	On string
+}
+
+// Select describes the from clause and projection for the join aware methods.
+// Filter keys and orderBy may then use the aliases, e.g. "a.status" or "u.name asc".
+type Select struct {
+	Table   string
+	As      string
+	Columns []string
+	Joins   []Join
+}
+
+var (
+	projectionRegexp = regexp.MustCompile(`(?i)^([a-zA-Z_][a-zA-Z0-9_]*\.)?([a-zA-Z_][a-zA-Z0-9_]*|\*)( +as +[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+	joinOnRegexp     = regexp.MustCompile(`(?i)^[a-zA-Z_][a-zA-Z0-9_.]* *= *[a-zA-Z_][a-zA-Z0-9_.]*( +and +[a-zA-Z_][a-zA-Z0-9_.]* *= *[a-zA-Z_][a-zA-Z0-9_.]*)*$`)
+)
+
+func quoteTable(tableName string, driverName string) string {
+	if driverName == "mysql" {
+		return "`" + tableName + "`"
+	}
+	return "\"" + tableName + "\""
+}
+
+func (s *Select) from(driverName string) (string, error) {
+	if err := checkColumn(s.Table); err != nil {
+		return "", err
+	}
+	from := quoteTable(s.Table, driverName)
+	if s.As != "" {
+		if err := checkColumn(s.As); err != nil {
+			return "", err
+		}
+		from += " as " + s.As
+	}
+	for _, join := range s.Joins {
+		joinType := strings.ToLower(join.Type)
+		switch joinType {
+		case "":
+			joinType = "inner"
+		case "inner", "left", "right":
+		default:
+			return "", errors.New("DBDriver: unsupported join type " + join.Type)
+		}
+		if err := checkColumn(join.Table); err != nil {
+			return "", err
+		}
+		if !joinOnRegexp.MatchString(strings.TrimSpace(join.On)) {
+			return "", errors.New("DBDriver: invalid join condition " + join.On)
+		}
+		from += " " + joinType + " join " + quoteTable(join.Table, driverName)
+		if join.As != "" {
+			if err := checkColumn(join.As); err != nil {
+				return "", err
+			}
+			from += " as " + join.As
+		}
+		from += " on " + strings.TrimSpace(join.On)
+	}
+	return from, nil
+}
+
+func (s *Select) columns() (string, error) {
+	if len(s.Columns) == 0 {
+		if s.As != "" {
+			return s.As + ".*", nil
+		}
+		return "*", nil
+	}
+	for _, column := range s.Columns {
+		if !projectionRegexp.MatchString(strings.TrimSpace(column)) {
+			return "", errors.New("DBDriver: invalid column " + column)
+		}
+	}
+	return strings.Join(s.Columns, ", "), nil
+}
+
+// GetSelectSQL returns the select statement for s filtered by query, without order or limit.
+func GetSelectSQL(s *Select, query map[string]interface{}, driverName string) (string, error) {
+	columns, err := s.columns()
+	if err != nil {
+		return "", err
+	}
+	from, err := s.from(driverName)
+	if err != nil {
+		return "", err
+	}
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return "", err
+	}
+	return "select " + columns + " from " + from + where, nil
+}
+
+func GetSelectCountSQL(s *Select, query map[string]interface{}, driverName string) (string, error) {
+	from, err := s.from(driverName)
+	if err != nil {
+		return "", err
+	}
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return "", err
+	}
+	return "select count(1) as number from " + from + where, nil
+}
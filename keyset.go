@@ -0,0 +1,225 @@
+package DBDriver
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+type KeysetPage struct {
+	Size    int64  `json:"size"`
+	Next    string `json:"next"`
+	Prev    string `json:"prev"`
+	HasNext bool   `json:"has_next"`
+	HasPrev bool   `json:"has_prev"`
+}
+
+type sortKey struct {
+	Column string
+	Desc   bool
+}
+
+type keysetCursor struct {
+	Values []interface{} `json:"v"`
+	Prev   bool          `json:"p,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("DBDriver: invalid cursor")
+
+// KeysetSigningKey, when set, signs keyset cursors with HMAC-SHA256 so that clients
+// cannot forge or alter them; cursors without a valid signature are then rejected.
+var KeysetSigningKey []byte
+
+var columnRegexp = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_.]*$")
+
+func parseSortKeys(orderBy string) ([]sortKey, error) {
+	keys := make([]sortKey, 0, 2)
+	hasId := false
+	for _, part := range strings.Split(orderBy, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 || !columnRegexp.MatchString(fields[0]) {
+			return nil, errors.New("DBDriver: invalid sort key " + strings.TrimSpace(part))
+		}
+		key := sortKey{Column: fields[0]}
+		if len(fields) == 2 {
+			switch strings.ToLower(fields[1]) {
+			case "asc":
+			case "desc":
+				key.Desc = true
+			default:
+				return nil, errors.New("DBDriver: invalid sort direction " + fields[1])
+			}
+		}
+		if key.Column == "id" {
+			hasId = true
+		}
+		keys = append(keys, key)
+	}
+	if !hasId {
+		keys = append(keys, sortKey{Column: "id"})
+	}
+	return keys, nil
+}
+
+func signCursor(payload string) string {
+	mac := hmac.New(sha256.New, KeysetSigningKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeCursor(keys []sortKey, row map[string]interface{}, prev bool) string {
+	c := keysetCursor{Values: make([]interface{}, len(keys)), Prev: prev}
+	for i, key := range keys {
+		c.Values[i] = row[key.Column]
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	cursor := base64.RawURLEncoding.EncodeToString(b)
+	if len(KeysetSigningKey) > 0 {
+		cursor += "." + signCursor(cursor)
+	}
+	return cursor
+}
+
+// decodeCursor checks the signature when KeysetSigningKey is set and only accepts
+// scalar values, which are bound as arguments and never written into the statement.
+func decodeCursor(cursor string, keys []sortKey) (*keysetCursor, error) {
+	if len(KeysetSigningKey) > 0 {
+		i := strings.LastIndexByte(cursor, '.')
+		if i < 0 || !hmac.Equal([]byte(cursor[i+1:]), []byte(signCursor(cursor[:i]))) {
+			return nil, ErrInvalidCursor
+		}
+		cursor = cursor[:i]
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &keysetCursor{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err = decoder.Decode(c); err != nil || len(c.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+	for i, v := range c.Values {
+		switch value := v.(type) {
+		case json.Number:
+			if n, err := value.Int64(); err == nil {
+				c.Values[i] = n
+			} else if f, err := value.Float64(); err == nil {
+				c.Values[i] = f
+			} else {
+				return nil, ErrInvalidCursor
+			}
+		case string, bool, nil:
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// keysetCondition expands the row comparison for mixed sort directions:
+// (a > x) or (a = x and b > y) or ... with the values bound after offset placeholders.
+func keysetCondition(keys []sortKey, values []interface{}, backward bool, driverName string, offset int) (string, []interface{}) {
+	s := ""
+	split := ""
+	args := make([]interface{}, 0, len(keys)*(len(keys)+1)/2)
+	bind := func(v interface{}) string {
+		args = append(args, v)
+		return placeholder(driverName, offset+len(args))
+	}
+	for i, key := range keys {
+		part := ""
+		for j := 0; j < i; j++ {
+			part += keys[j].Column + " = " + bind(values[j]) + " and "
+		}
+		op := ">"
+		if key.Desc != backward {
+			op = "<"
+		}
+		part += key.Column + " " + op + " " + bind(values[i])
+		s += split + "(" + part + ")"
+		split = " or "
+	}
+	return "(" + s + ")", args
+}
+
+func keysetOrderBy(keys []sortKey, backward bool) string {
+	s := ""
+	split := ""
+	for _, key := range keys {
+		dir := "asc"
+		if key.Desc != backward {
+			dir = "desc"
+		}
+		s += split + key.Column + " " + dir
+		split = ", "
+	}
+	return s
+}
+
+// keysetSQL returns the where, order by and limit part of a keyset page query and
+// the arguments for the cursor values.
+func keysetSQL(query map[string]interface{}, orderBy string, cursor string, size int64, driverName string) (string, []interface{}, []sortKey, bool, error) {
+	keys, err := parseSortKeys(orderBy)
+	if err != nil {
+		return "", nil, nil, false, err
+	}
+	backward := false
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return "", nil, nil, false, err
+	}
+	var args []interface{}
+	if cursor != "" {
+		c, err := decodeCursor(cursor, keys)
+		if err != nil {
+			return "", nil, nil, false, err
+		}
+		backward = c.Prev
+		var condition string
+		condition, args = keysetCondition(keys, c.Values, backward, driverName, 0)
+		where = appendWhere(where, condition)
+	}
+	return where + " order by " + keysetOrderBy(keys, backward) + " limit " + SqlQuote(size+1), args, keys, backward, nil
+}
+
+func keysetResult(list []map[string]interface{}, keys []sortKey, size int64, cursor string, backward bool) ([]map[string]interface{}, *KeysetPage) {
+	hasMore := int64(len(list)) > size
+	if hasMore {
+		list = list[:size]
+	}
+	if backward {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+	page := &KeysetPage{Size: size}
+	if backward {
+		page.HasPrev = hasMore
+		page.HasNext = true
+	} else {
+		page.HasNext = hasMore
+		page.HasPrev = cursor != ""
+	}
+	if len(list) > 0 {
+		if page.HasNext {
+			page.Next = encodeCursor(keys, list[len(list)-1], false)
+		}
+		if page.HasPrev {
+			page.Prev = encodeCursor(keys, list[0], true)
+		}
+	}
+	return list, page
+}
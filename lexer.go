@@ -0,0 +1,115 @@
+package DBDriver
+
+import "strings"
+
+// sqlSegment is a piece of a statement; only code segments may hold placeholders,
+// literals, quoted identifiers and comments are copied untouched.
+type sqlSegment struct {
+	code bool
+	text string
+}
+
+func segmentSQL(query string, driverName string) []sqlSegment {
+	mysql := driverName == "mysql"
+	segments := make([]sqlSegment, 0, 4)
+	start := 0
+	flush := func(end int, code bool) {
+		if end > start {
+			segments = append(segments, sqlSegment{code: code, text: query[start:end]})
+		}
+		start = end
+	}
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		begin := i
+		end := -1
+		switch {
+		case c == '\'' && !mysql && escapePrefix(query, i):
+			begin = i - 1
+			end = quotedEnd(query, i, c, true)
+		case c == '\'' || c == '"' || (c == '`' && mysql):
+			end = quotedEnd(query, i, c, mysql && c != '`')
+		case c == '-' && strings.HasPrefix(query[i:], "--"), c == '#' && mysql:
+			end = strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 1
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end = strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 4
+			}
+		case c == '$' && !mysql:
+			end = dollarQuotedEnd(query, i)
+		}
+		if end < 0 {
+			i++
+			continue
+		}
+		flush(begin, true)
+		i = end
+		flush(i, false)
+	}
+	flush(len(query), true)
+	return segments
+}
+
+// escapePrefix reports whether the quote at i opens a Postgres E'...' string, in
+// which backslashes escape.
+func escapePrefix(query string, i int) bool {
+	if i == 0 || (query[i-1] != 'E' && query[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !(isLetter(query[i-2]) || isDigit(query[i-2]) || query[i-2] == '_')
+}
+
+// quotedEnd returns the index after the closing quote, treating a doubled quote
+// and, when backslash is set, a backslash as escapes.
+func quotedEnd(query string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// dollarQuotedEnd returns the end of a $tag$...$tag$ string starting at i, or -1
+// when the $ does not open one (e.g. a $1 placeholder).
+func dollarQuotedEnd(query string, i int) int {
+	j := i + 1
+	for j < len(query) && (query[j] == '_' || isLetter(query[j]) || (j > i+1 && isDigit(query[j]))) {
+		j++
+	}
+	if j >= len(query) || query[j] != '$' {
+		return -1
+	}
+	tag := query[i : j+1]
+	end := strings.Index(query[j+1:], tag)
+	if end < 0 {
+		return len(query)
+	}
+	return j + 1 + end + len(tag)
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
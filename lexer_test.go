@@ -0,0 +1,41 @@
+package DBDriver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegmentSQL(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		driverName string
+		want       []sqlSegment
+	}{
+		{"plain", "select 1", "postgres", []sqlSegment{{true, "select 1"}}},
+		{"string", "a = 'x?' and b", "postgres", []sqlSegment{{true, "a = "}, {false, "'x?'"}, {true, " and b"}}},
+		{"doubled quote", "'it''s' x", "postgres", []sqlSegment{{false, "'it''s'"}, {true, " x"}}},
+		{"escape string", `E'it\'s' x`, "postgres", []sqlSegment{{false, `E'it\'s'`}, {true, " x"}}},
+		{"lower escape string", `x = e'\'' y`, "postgres", []sqlSegment{{true, "x = "}, {false, `e'\''`}, {true, " y"}}},
+		{"identifier ending in e", `name'a\' b`, "postgres", []sqlSegment{{true, "name"}, {false, `'a\'`}, {true, " b"}}},
+		{"mysql backslash", `'a\'b' c`, "mysql", []sqlSegment{{false, `'a\'b'`}, {true, " c"}}},
+		{"mysql backtick", "`a?` b", "mysql", []sqlSegment{{false, "`a?`"}, {true, " b"}}},
+		{"postgres backtick", "`a` b", "postgres", []sqlSegment{{true, "`a` b"}}},
+		{"quoted identifier", `"a?" b`, "postgres", []sqlSegment{{false, `"a?"`}, {true, " b"}}},
+		{"line comment", "a -- b?\nc", "postgres", []sqlSegment{{true, "a "}, {false, "-- b?\n"}, {true, "c"}}},
+		{"hash comment", "a # b?\nc", "mysql", []sqlSegment{{true, "a "}, {false, "# b?\n"}, {true, "c"}}},
+		{"hash operator", "a # b", "postgres", []sqlSegment{{true, "a # b"}}},
+		{"block comment", "a /* ? */ b", "postgres", []sqlSegment{{true, "a "}, {false, "/* ? */"}, {true, " b"}}},
+		{"unterminated comment", "a /* b", "postgres", []sqlSegment{{true, "a "}, {false, "/* b"}}},
+		{"dollar quote", "a $$ ? $$ b", "postgres", []sqlSegment{{true, "a "}, {false, "$$ ? $$"}, {true, " b"}}},
+		{"tagged dollar quote", "$x$ $$ $x$ b", "postgres", []sqlSegment{{false, "$x$ $$ $x$"}, {true, " b"}}},
+		{"placeholder", "a = $1", "postgres", []sqlSegment{{true, "a = $1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := segmentSQL(tt.query, tt.driverName); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("segmentSQL(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// sqlConn is satisfied by *sql.DB and *sql.Tx.
+type sqlConn interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}
+
+type logLevelKey struct{}
+
+// WithLogLevel overrides the driver's statement log level for calls made with ctx,
+// through QueryContext, ExecContext or any of the XContext methods.
+func WithLogLevel(ctx context.Context, level slog.Level) context.Context {
+	return context.WithValue(ctx, logLevelKey{}, level)
+}
+
+// showSqlLogger backs ShowSql when no Logger was set.
+var showSqlLogger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+// RedactArgs replaces every bound argument with its type, for use as a driver's Redact.
+// Whenever Redact is set, statements are logged as their Fingerprint, so values the
+// map based methods inline into the SQL are hidden as well.
+func RedactArgs(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg == nil {
+			continue
+		}
+		redacted[i] = "[" + slog.AnyValue(arg).Kind().String() + "]"
+	}
+	return redacted
+}
+
+type statementLog struct {
+	Logger *slog.Logger
+	Level  slog.Level
+	Redact func([]interface{}) []interface{}
+}
+
+// log records one statement. Failed statements are logged at least at error level,
+// rowsAffected is left out when negative.
+func (l statementLog) log(ctx context.Context, query string, args []interface{}, duration time.Duration, rowsAffected int64, err error) {
+	if l.Logger == nil {
+		return
+	}
+	level := l.Level
+	if v, ok := ctx.Value(logLevelKey{}).(slog.Level); ok {
+		level = v
+	}
+	if err != nil && level < slog.LevelError {
+		level = slog.LevelError
+	}
+	if !l.Logger.Enabled(ctx, level) {
+		return
+	}
+	if l.Redact != nil {
+		query = Fingerprint(query)
+		args = l.Redact(args)
+	}
+	attrs := []slog.Attr{
+		slog.String("sql", query),
+		slog.Any("args", args),
+		slog.Duration("duration", duration),
+	}
+	if rowsAffected >= 0 {
+		attrs = append(attrs, slog.Int64("rows_affected", rowsAffected))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	l.Logger.LogAttrs(ctx, level, "sql", attrs...)
+}
@@ -0,0 +1,187 @@
+package DBDriver
+
+import (
+	"database/sql"
+	"expvar"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives per statement observations and the connection pools of a driver.
+// A Prometheus backend would turn ObserveQuery into histogram and counter vectors
+// labelled by driver, operation and table, and RegisterPool into gauge funcs.
+// Pools are named by driver and address, e.g. mysql/tcp(db1:3306)/shop, so the
+// primary and replicas of several drivers of one kind stay apart.
+type Metrics interface {
+	ObserveQuery(driver, operation, table string, duration time.Duration, err error)
+	RegisterPool(name string, stats func() sql.DBStats)
+}
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, used by ExpvarMetrics.
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// observeStatement reports a finished statement to metrics, along with the breaker
+// and limiter state when metrics implements StateMetrics.
+func observeStatement(metrics Metrics, driverName, dataSourceName string, breaker *CircuitBreaker, limiter *Limiter, event *QueryEvent) {
+	if metrics == nil {
+		return
+	}
+	metrics.ObserveQuery(driverName, event.Operation, event.Table, event.Duration, event.Err)
+	m, ok := metrics.(StateMetrics)
+	if !ok || (breaker == nil && limiter == nil) {
+		return
+	}
+	name := poolName(driverName, dataSourceName)
+	if breaker != nil {
+		m.SetBreakerState(name, breaker.State())
+	}
+	if limiter != nil {
+		m.SetLimiterState(name, limiter.InFlight(), limiter.Queued())
+	}
+}
+
+// poolName names a pool for RegisterPool by driver and address, leaving out the
+// credentials in dataSourceName.
+func poolName(driverName, dataSourceName string) string {
+	if driverName == "postgres" && !strings.Contains(dataSourceName, "://") {
+		settings := map[string]string{}
+		for _, field := range strings.Fields(dataSourceName) {
+			if k, v, ok := strings.Cut(field, "="); ok {
+				settings[k] = v
+			}
+		}
+		return driverName + "/" + settings["host"] + ":" + settings["port"] + "/" + settings["dbname"]
+	}
+	addr := dataSourceName[strings.LastIndex(dataSourceName, "@")+1:]
+	if i := strings.IndexByte(addr, '?'); i >= 0 {
+		addr = addr[:i]
+	}
+	return driverName + "/" + addr
+}
+
+// registerPools hands the primary and every replica to metrics. Replicas added to
+// Replicas directly have no known address and are named after their position.
+func registerPools(metrics Metrics, driverName, dataSourceName string, primary *sql.DB, replicas []*sql.DB, replicaNames []string) {
+	name := poolName(driverName, dataSourceName)
+	metrics.RegisterPool(name, primary.Stats)
+	for i, replica := range replicas {
+		if i < len(replicaNames) {
+			metrics.RegisterPool(replicaNames[i], replica.Stats)
+		} else {
+			metrics.RegisterPool(name+"/replica"+strconv.Itoa(i), replica.Stats)
+		}
+	}
+}
+
+type expvarHistogram struct {
+	Count   int64            `json:"count"`
+	Errors  int64            `json:"errors"`
+	Sum     float64          `json:"sum"`
+	Buckets map[string]int64 `json:"buckets"`
+	bounds  []float64
+}
+
+// ExpvarMetrics publishes statement histograms and pool stats under one expvar name,
+// keyed by "driver/operation/table" and pool name respectively.
+type ExpvarMetrics struct {
+	Buckets []float64
+
+	mu       sync.Mutex
+	queries  map[string]*expvarHistogram
+	pools    map[string]func() sql.DBStats
+	breakers map[string]string
+	limiters map[string][2]int64
+}
+
+// NewExpvarMetrics publishes the metrics as name; like expvar.Publish it panics when
+// name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	m := &ExpvarMetrics{
+		Buckets:  DefaultLatencyBuckets,
+		queries:  map[string]*expvarHistogram{},
+		pools:    map[string]func() sql.DBStats{},
+		breakers: map[string]string{},
+		limiters: map[string][2]int64{},
+	}
+	expvar.Publish(name, expvar.Func(m.snapshot))
+	return m
+}
+
+func (m *ExpvarMetrics) ObserveQuery(driver, operation, table string, duration time.Duration, err error) {
+	key := driver + "/" + operation + "/" + table
+	seconds := duration.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.queries[key]
+	if !ok {
+		h = &expvarHistogram{Buckets: map[string]int64{}, bounds: m.Buckets}
+		m.queries[key] = h
+	}
+	h.Count++
+	h.Sum += seconds
+	if err != nil {
+		h.Errors++
+	}
+	for _, bound := range h.bounds {
+		if seconds <= bound {
+			h.Buckets[strconv.FormatFloat(bound, 'g', -1, 64)]++
+		}
+	}
+	h.Buckets["+Inf"]++
+}
+
+func (m *ExpvarMetrics) RegisterPool(name string, stats func() sql.DBStats) {
+	m.mu.Lock()
+	m.pools[name] = stats
+	m.mu.Unlock()
+}
+
+func (m *ExpvarMetrics) SetBreakerState(name string, state BreakerState) {
+	m.mu.Lock()
+	m.breakers[name] = state.String()
+	m.mu.Unlock()
+}
+
+func (m *ExpvarMetrics) SetLimiterState(name string, inFlight, queued int64) {
+	m.mu.Lock()
+	m.limiters[name] = [2]int64{inFlight, queued}
+	m.mu.Unlock()
+}
+
+func (m *ExpvarMetrics) snapshot() interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	queries := make(map[string]expvarHistogram, len(m.queries))
+	for key, h := range m.queries {
+		buckets := make(map[string]int64, len(h.Buckets))
+		for b, n := range h.Buckets {
+			buckets[b] = n
+		}
+		queries[key] = expvarHistogram{Count: h.Count, Errors: h.Errors, Sum: h.Sum, Buckets: buckets}
+	}
+	pools := make(map[string]interface{}, len(m.pools))
+	for name, stats := range m.pools {
+		s := stats()
+		pools[name] = map[string]interface{}{
+			"max_open":            s.MaxOpenConnections,
+			"open":                s.OpenConnections,
+			"in_use":              s.InUse,
+			"idle":                s.Idle,
+			"wait_count":          s.WaitCount,
+			"wait_duration":       s.WaitDuration.Seconds(),
+			"max_idle_closed":     s.MaxIdleClosed,
+			"max_lifetime_closed": s.MaxLifetimeClosed,
+		}
+	}
+	breakers := make(map[string]string, len(m.breakers))
+	for name, state := range m.breakers {
+		breakers[name] = state
+	}
+	limiters := make(map[string]interface{}, len(m.limiters))
+	for name, state := range m.limiters {
+		limiters[name] = map[string]int64{"in_flight": state[0], "queued": state[1]}
+	}
+	return map[string]interface{}{"queries": queries, "pools": pools, "breakers": breakers, "limiters": limiters}
+}
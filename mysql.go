@@ -1,10 +1,15 @@
 package DBDriver
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"log"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,6 +19,30 @@ type MysqlDriver struct {
 	Show           bool
 	DB             *sql.DB
 	SQLTX          *sql.Tx
+	Safe           bool
+	MaxAffected    int64
+	Relations      map[string][]Relation
+	Rebind         bool
+	Logger         *slog.Logger
+	LogLevel       slog.Level
+	Redact         func([]interface{}) []interface{}
+	SlowLog        *SlowLog
+	Hooks          []Hook
+	Metrics        Metrics
+	Retry          *RetryPolicy
+	Breaker        *CircuitBreaker
+	Limiter        *Limiter
+	Replicas       []*sql.DB
+	Balancer       Balancer
+	replicaNames   []string
+	releaseTx      func()
+	version        *serverVersion
+}
+
+// serverVersion caches select version(); copies of the driver share it.
+type serverVersion struct {
+	mu      sync.Mutex
+	version string
 }
 
 func InitMysqlDriver(host string, port int, user, password, dbname string) *MysqlDriver {
@@ -40,10 +69,14 @@ func (db *MysqlDriver) Open() (err error) {
 	db.DB.SetMaxOpenConns(20)
 	db.DB.SetMaxIdleConns(10)
 	db.DB.SetConnMaxLifetime(time.Second * 10)
+	db.version = &serverVersion{}
 	return nil
 }
 
 func (db *MysqlDriver) Close() error {
+	for _, replica := range db.Replicas {
+		replica.Close()
+	}
 	return db.DB.Close()
 }
 
@@ -57,29 +90,193 @@ func (db *MysqlDriver) HideSql() error {
 	return nil
 }
 
-func (db *MysqlDriver) Query(query string, args ...interface{}) (*sql.Rows, error) {
+// SetLogger sends every statement to logger at level; failed statements use error level.
+func (db *MysqlDriver) SetLogger(logger *slog.Logger, level slog.Level) error {
+	db.Logger = logger
+	db.LogLevel = level
+	return nil
+}
+
+func (db *MysqlDriver) SetSlowLog(slowLog *SlowLog) error {
+	db.SlowLog = slowLog
+	return nil
+}
+
+// SlowQueries returns the n statement fingerprints with the most total time, see SlowLog.Top.
+func (db *MysqlDriver) SlowQueries(n int) []QueryStats {
+	return db.SlowLog.Top(n)
+}
+
+func (db *MysqlDriver) statementLog() statementLog {
+	if db.Logger != nil {
+		return statementLog{Logger: db.Logger, Level: db.LogLevel, Redact: db.Redact}
+	}
 	if db.Show {
-		fmt.Println(query)
-		fmt.Println(args...)
+		return statementLog{Logger: showSqlLogger, Level: slog.LevelInfo, Redact: db.Redact}
+	}
+	return statementLog{}
+}
+
+func (db *MysqlDriver) query(ctx context.Context, conn sqlConn, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	retry := retryPolicyFor(db.Retry, ctx, conn, isReadStatement(query))
+	err := retry.do(ctx, func() error {
+		event := newQueryEvent(ctx, "Query", conn, query, args)
+		err := runHooks(ctx, db.Hooks, event, func(ctx context.Context) error {
+			var err error
+			start := time.Now()
+			rows, err = guardedQuery(ctx, db.Breaker, db.Limiter, conn, event.SQL, event.Args)
+			duration := time.Since(start)
+			db.statementLog().log(ctx, event.SQL, event.Args, duration, -1, err)
+			db.SlowLog.observe(ctx, db.Logger, event.SQL, db.Redact != nil, duration, err)
+			return ClassifyError(err)
+		})
+		observeStatement(db.Metrics, db.DriverName, db.DataSourceName, db.Breaker, db.Limiter, event)
+		return err
+	})
+	if err != nil && rows != nil {
+		rows.Close()
+		rows = nil
+	}
+	return rows, ClassifyError(err)
+}
+
+func (db *MysqlDriver) exec(ctx context.Context, conn sqlConn, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	retry := retryPolicyFor(db.Retry, ctx, conn, false)
+	err := retry.do(ctx, func() error {
+		event := newQueryEvent(ctx, "Exec", conn, query, args)
+		err := runHooks(ctx, db.Hooks, event, func(ctx context.Context) error {
+			var err error
+			start := time.Now()
+			result, err = guardedExec(ctx, db.Breaker, db.Limiter, conn, event.SQL, event.Args)
+			duration := time.Since(start)
+			if err == nil {
+				if n, e := result.RowsAffected(); e == nil {
+					event.RowsAffected = n
+				}
+			}
+			db.statementLog().log(ctx, event.SQL, event.Args, duration, event.RowsAffected, err)
+			db.SlowLog.observe(ctx, db.Logger, event.SQL, db.Redact != nil, duration, err)
+			return ClassifyError(err)
+		})
+		observeStatement(db.Metrics, db.DriverName, db.DataSourceName, db.Breaker, db.Limiter, event)
+		return err
+	})
+	if err != nil {
+		return nil, ClassifyError(err)
+	}
+	return result, nil
+}
+
+func (db *MysqlDriver) queryWith(ctx context.Context) func(string, ...interface{}) (*sql.Rows, error) {
+	return func(query string, args ...interface{}) (*sql.Rows, error) {
+		return db.QueryContext(ctx, query, args...)
+	}
+}
+
+func (db *MysqlDriver) AddHook(hook Hook) error {
+	db.Hooks = append(db.Hooks, hook)
+	return nil
+}
+
+func (db *MysqlDriver) SetRetryPolicy(policy *RetryPolicy) error {
+	db.Retry = policy
+	return nil
+}
+
+// SetCircuitBreaker puts breaker in front of every statement, replacing any earlier
+// one; nil removes it. Its state is reported to the driver's Metrics when that
+// implements StateMetrics.
+func (db *MysqlDriver) SetCircuitBreaker(breaker *CircuitBreaker) error {
+	db.Breaker = breaker
+	return nil
+}
+
+// SetLimiter bounds the statements running at once, see Limiter. It replaces any
+// earlier limiter; nil removes it.
+func (db *MysqlDriver) SetLimiter(limiter *Limiter) error {
+	db.Limiter = limiter
+	return nil
+}
+
+// RunInTx runs fn in a new transaction and commits it when fn returns nil. With a
+// retry policy and a RetrySafe ctx the transaction is repeated on transient failures.
+func (db *MysqlDriver) RunInTx(ctx context.Context, fn func(*Tx) error) error {
+	return runInTx(ctx, db.Retry, db.beginTx, func(tx *sql.Tx) error {
+		return fn(&Tx{Tx: tx, driverName: db.DriverName, rebind: db.Rebind, query: db.query, exec: db.exec})
+	})
+}
+
+// beginTx begins a transaction behind the breaker and limiter, see beginTx.
+func (db *MysqlDriver) beginTx(ctx context.Context) (*sql.Tx, func(), error) {
+	return beginTx(ctx, db.Breaker, db.Limiter, db.DB)
+}
+
+// SetMetrics reports every statement and the primary and replica pools to metrics,
+// replacing any earlier Metrics; nil turns reporting off.
+func (db *MysqlDriver) SetMetrics(metrics Metrics) error {
+	db.Metrics = metrics
+	if metrics != nil {
+		registerPools(metrics, db.DriverName, db.DataSourceName, db.DB, db.Replicas, db.replicaNames)
+	}
+	return nil
+}
+
+func (db *MysqlDriver) Stats() sql.DBStats {
+	return db.DB.Stats()
+}
+
+func (db *MysqlDriver) SetSafe(safe bool) error {
+	db.Safe = safe
+	return nil
+}
+
+func (db *MysqlDriver) SetMaxAffected(max int64) error {
+	db.MaxAffected = max
+	return nil
+}
+
+// SetRebind lets raw statements use ? placeholders on every driver, see RebindSQL.
+func (db *MysqlDriver) SetRebind(rebind bool) error {
+	db.Rebind = rebind
+	return nil
+}
+
+func (db *MysqlDriver) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *MysqlDriver) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if db.Rebind {
+		query = RebindSQL(query, db.DriverName)
 	}
-	return db.DB.Query(query, args...)
+	return db.query(ctx, readConn(ctx, db.DB, db.Replicas, db.Balancer, query), query, args...)
 }
 
 func (db *MysqlDriver) Exec(query string, args ...interface{}) (sql.Result, error) {
-	if db.Show {
-		fmt.Println(query)
-		fmt.Println(args...)
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *MysqlDriver) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if db.Rebind {
+		query = RebindSQL(query, db.DriverName)
 	}
-	return db.DB.Exec(query, args...)
+	return db.exec(ctx, db.DB, query, args...)
 }
 
 func (db *MysqlDriver) QueryMap(tableName string, query map[string]interface{}) (*sql.Rows, error) {
+	return db.QueryMapContext(context.Background(), tableName, query)
+}
+
+func (db *MysqlDriver) QueryMapContext(ctx context.Context, tableName string, query map[string]interface{}) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "QueryMap", tableName)
 	s := "select * from " + tableName
-	where, _ := WhereFromQuery(query)
-	if db.Show {
-		fmt.Println(s + where)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
 	}
-	rows, err := db.Query(s + where)
+	rows, err := db.QueryContext(ctx, s+where)
 	if err != nil {
 		return nil, err
 	}
@@ -88,12 +285,13 @@ func (db *MysqlDriver) QueryMap(tableName string, query map[string]interface{})
 }
 
 func (db *MysqlDriver) FindById(tableName string, id int64) (*sql.Rows, error) {
+	return db.FindByIdContext(context.Background(), tableName, id)
+}
+
+func (db *MysqlDriver) FindByIdContext(ctx context.Context, tableName string, id int64) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "FindById", tableName)
 	s := "select * from " + tableName + " where id = ? limit 1 "
-	if db.Show {
-		fmt.Println(s)
-		fmt.Println(id)
-	}
-	rows, err := db.Query(s, id)
+	rows, err := db.QueryContext(ctx, s, id)
 	if err != nil {
 
 		return nil, err
@@ -102,15 +300,23 @@ func (db *MysqlDriver) FindById(tableName string, id int64) (*sql.Rows, error) {
 }
 
 func (db *MysqlDriver) FindOne(tableName string, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	return db.FindOneContext(context.Background(), tableName, query, orderBy)
+}
+
+func (db *MysqlDriver) FindOneContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "FindOne", tableName)
 	s := "select * from " + tableName
 	if !CheckOrderBy(orderBy) {
 		orderBy = ""
 	}
-	where, _ := WhereFromQuery(query)
-	if db.Show {
-		fmt.Println(s + where)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if orderBy != "" {
+		where += " order by " + orderBy
 	}
-	rows, err := db.DB.Query(s + where)
+	rows, err := db.QueryContext(ctx, s+where)
 	if err != nil {
 		return nil, err
 	}
@@ -118,15 +324,23 @@ func (db *MysqlDriver) FindOne(tableName string, query map[string]interface{}, o
 }
 
 func (db *MysqlDriver) GetList(tableName string, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	return db.GetListContext(context.Background(), tableName, query, orderBy)
+}
+
+func (db *MysqlDriver) GetListContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "GetList", tableName)
 	s := "select * from " + tableName
 	if !CheckOrderBy(orderBy) {
 		orderBy = ""
 	}
-	where, _ := WhereFromQuery(query)
-	if db.Show {
-		fmt.Println(s + where)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if orderBy != "" {
+		where += " order by " + orderBy
 	}
-	rows, err := db.DB.Query(s + where)
+	rows, err := db.QueryContext(ctx, s+where)
 	if err != nil {
 		return nil, err
 	}
@@ -134,60 +348,69 @@ func (db *MysqlDriver) GetList(tableName string, query map[string]interface{}, o
 }
 
 func (db *MysqlDriver) GetPage(tableName string, query map[string]interface{}, orderBy string, page, size int64) (*sql.Rows, *Page, error) {
-	var total, last, prev, next int64
-	total, _ = db.Count(tableName, query)
-	last = total/size + 1
-	prev = 1
-	if page > 2 {
-		prev = page - 1
-	} else {
-		page = 1
-	}
-	next = last
-	if page < last-1 {
-		next = page + 1
+	return db.GetPageContext(context.Background(), tableName, query, orderBy, page, size)
+}
+
+func (db *MysqlDriver) GetPageContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, page, size int64) (*sql.Rows, *Page, error) {
+	ctx = withOperation(ctx, "GetPage", tableName)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	total, err := db.CountContext(ctx, tableName, query)
+	if err != nil {
+		return nil, nil, err
 	}
-	offset := (page - 1) * size
+	p := NewPage(page, size, total)
+	offset := (p.Page - 1) * p.Size
 	s := "select * from " + tableName
 	if !CheckOrderBy(orderBy) {
 		orderBy = ""
 	}
-	where, _ := WhereFromQuery(query)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
 	sql2 := s + where
 	if orderBy != "" {
 		sql2 += " order by " + orderBy
 	}
 	sql2 += " limit ? offset ?"
-	if db.Show {
-		fmt.Println(sql2)
-		fmt.Println(size,offset)
-	}
-	rows, err := db.DB.Query(sql2, size, offset)
+	rows, err := db.QueryContext(ctx, sql2, p.Size, offset)
 	if err != nil {
 		return nil, nil, err
 	}
-	return rows, &Page{First: 1, Prev: prev, Page: page, Next: next, Last: last, Size: size, Total: total}, nil
+	return rows, p, nil
 }
 
 func (db *MysqlDriver) Count(tableName string, query map[string]interface{}) (int64, error) {
+	return db.CountContext(context.Background(), tableName, query)
+}
+
+func (db *MysqlDriver) CountContext(ctx context.Context, tableName string, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "Count", tableName)
 	var count int64 = 0
 	s := "select count(1) as number from " + tableName
-	where, _ := WhereFromQuery(query)
-	if db.Show {
-		fmt.Println(s + where)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return 0, err
 	}
-	rows, err := db.DB.Query(s + where)
+	rows, err := db.QueryContext(ctx, s+where)
 	if err != nil {
 		return 0, err
 	}
 	defer rows.Close()
-	rows.Next()
-	_ = rows.Scan(&count)
-	return count, nil
+	if rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, rows.Err()
 }
 
 func (db *MysqlDriver) Exists(tableName string, query map[string]interface{}) bool {
-	c, err := db.Count(tableName, query)
+	return db.ExistsContext(context.Background(), tableName, query)
+}
+
+func (db *MysqlDriver) ExistsContext(ctx context.Context, tableName string, query map[string]interface{}) bool {
+	c, err := db.CountContext(ctx, tableName, query)
 	if err != nil {
 		return false
 	}
@@ -195,11 +418,13 @@ func (db *MysqlDriver) Exists(tableName string, query map[string]interface{}) bo
 }
 
 func (db *MysqlDriver) Insert(tableName string, post map[string]interface{}) (int64, error) {
+	return db.InsertContext(context.Background(), tableName, post)
+}
+
+func (db *MysqlDriver) InsertContext(ctx context.Context, tableName string, post map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "Insert", tableName)
 	s, _ := GetInsertSql(tableName, post,"mysql")
-	if db.Show {
-		fmt.Println(s)
-	}
-	exec, err := db.DB.Exec(s)
+	exec, err := db.ExecContext(ctx, s)
 	if err != nil {
 		return 0, err
 	}
@@ -207,52 +432,80 @@ func (db *MysqlDriver) Insert(tableName string, post map[string]interface{}) (in
 }
 
 func (db *MysqlDriver) Update(tableName string, post map[string]interface{}, query map[string]interface{}) (int64, error) {
-	s, _ := GetUpdateSQL(tableName, post, query,"mysql")
-	if db.Show {
-		fmt.Println(s)
-	}
-	exec, err := db.DB.Exec(s)
-	if err != nil {
+	return db.UpdateContext(context.Background(), tableName, post, query)
+}
+
+func (db *MysqlDriver) UpdateContext(ctx context.Context, tableName string, post map[string]interface{}, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "Update", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
 		return 0, err
 	}
-	return exec.RowsAffected()
+	s, _ := GetUpdateSQL(tableName, post, query, "mysql")
+	return execLimited(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected)
+}
+
+func (db *MysqlDriver) UpdateAll(tableName string, post map[string]interface{}) (int64, error) {
+	return db.UpdateAllContext(context.Background(), tableName, post)
+}
+
+func (db *MysqlDriver) UpdateAllContext(ctx context.Context, tableName string, post map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "UpdateAll", tableName)
+	s, _ := GetUpdateSQL(tableName, post, map[string]interface{}{}, "mysql")
+	return execLimited(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected)
 }
 
 func (db *MysqlDriver) Save(tableName string, post map[string]interface{}) (int64, error) {
+	return db.SaveContext(context.Background(), tableName, post)
+}
+
+func (db *MysqlDriver) SaveContext(ctx context.Context, tableName string, post map[string]interface{}) (int64, error) {
 	id, ok := post["id"]
 	if ok {
 		delete(post, "id")
-		return db.Update(tableName, post, map[string]interface{}{"id": id})
+		return db.UpdateContext(ctx, tableName, post, map[string]interface{}{"id": id})
 	} else {
-		return db.Insert(tableName, post)
+		return db.InsertContext(ctx, tableName, post)
 	}
 }
 
 func (db *MysqlDriver) Delete(tableName string, query map[string]interface{}) (int64, error) {
-	where, _ := WhereFromQuery(query)
+	return db.DeleteContext(context.Background(), tableName, query)
+}
+
+func (db *MysqlDriver) DeleteContext(ctx context.Context, tableName string, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "Delete", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return 0, err
+	}
 	if where != "" {
 		s := "delete from " + tableName + where
-		if db.Show {
-			fmt.Println(s)
-		}
-		exec, err := db.DB.Exec(s)
-		if err != nil {
-			return 0, err
-		}
-		return exec.RowsAffected()
+		return execLimited(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected)
 	} else {
 		return 0, nil
 	}
 }
 
+func (db *MysqlDriver) DeleteAll(tableName string) (int64, error) {
+	return db.DeleteAllContext(context.Background(), tableName)
+}
+
+func (db *MysqlDriver) DeleteAllContext(ctx context.Context, tableName string) (int64, error) {
+	ctx = withOperation(ctx, "DeleteAll", tableName)
+	s := "delete from " + tableName
+	return execLimited(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected)
+}
+
 func (db *MysqlDriver) DeleteById(tableName string, id int64) (int64, error) {
+	return db.DeleteByIdContext(context.Background(), tableName, id)
+}
+
+func (db *MysqlDriver) DeleteByIdContext(ctx context.Context, tableName string, id int64) (int64, error) {
+	ctx = withOperation(ctx, "DeleteById", tableName)
 	if id != 0 {
 		s := "delete from " + tableName + " where id = ?"
-		if db.Show {
-			fmt.Println(s)
-			fmt.Println(id)
-		}
-		exec, err := db.DB.Exec(s, id)
+		exec, err := db.ExecContext(ctx, s, id)
 		if err != nil {
 			return 0, err
 		}
@@ -267,7 +520,7 @@ func (db *MysqlDriver) Begin() error {
 	if err != nil {
 		return nil
 	}
-	db.SQLTX, err = db.DB.Begin()
+	db.SQLTX, db.releaseTx, err = db.beginTx(context.Background())
 	if err != nil {
 		return err
 	}
@@ -275,17 +528,684 @@ func (db *MysqlDriver) Begin() error {
 }
 
 func (db *MysqlDriver) RollBack() error {
+	defer db.endTx()
 	return db.SQLTX.Rollback()
 }
 
 func (db *MysqlDriver) Commit() error {
-	return db.SQLTX.Commit()
+	defer db.endTx()
+	return ClassifyError(db.SQLTX.Commit())
+}
+
+// endTx gives back the limiter slot held by the transaction Begin started.
+func (db *MysqlDriver) endTx() {
+	if db.releaseTx != nil {
+		db.releaseTx()
+		db.releaseTx = nil
+	}
 }
 
 func (db *MysqlDriver) QueryTX(query string, args ...interface{}) (*sql.Rows, error) {
-	return db.SQLTX.Query(query, args...)
+	ctx := withOperation(context.Background(), "QueryTX", "")
+	if db.Rebind {
+		query = RebindSQL(query, db.DriverName)
+	}
+	return db.query(ctx, db.SQLTX, query, args...)
 }
 
 func (db *MysqlDriver) ExecTX(query string, args ...interface{}) (sql.Result, error) {
-	return db.SQLTX.Exec(query, args...)
+	ctx := withOperation(context.Background(), "ExecTX", "")
+	if db.Rebind {
+		query = RebindSQL(query, db.DriverName)
+	}
+	return db.exec(ctx, db.SQLTX, query, args...)
+}
+
+func (db *MysqlDriver) ServerVersion() (string, error) {
+	ctx := withOperation(context.Background(), "ServerVersion", "")
+	cache := db.version
+	if cache == nil {
+		cache = &serverVersion{}
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.version == "" {
+		err := queryScalar(db.queryWith(ctx), "select version()", &cache.version)
+		if err != nil {
+			return "", err
+		}
+	}
+	return cache.version, nil
+}
+
+func (db *MysqlDriver) IsMariaDB() bool {
+	version, err := db.ServerVersion()
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(version), "mariadb")
+}
+
+func (db *MysqlDriver) mariaDBAtLeast(major, minor int) bool {
+	version, err := db.ServerVersion()
+	if err != nil || !strings.Contains(strings.ToLower(version), "mariadb") {
+		return false
+	}
+	var ma, mi int
+	_, err = fmt.Sscanf(version, "%d.%d", &ma, &mi)
+	if err != nil {
+		return false
+	}
+	return ma > major || (ma == major && mi >= minor)
+}
+
+func (db *MysqlDriver) InsertReturning(tableName string, post map[string]interface{}) (map[string]interface{}, error) {
+	return db.InsertReturningContext(context.Background(), tableName, post)
+}
+
+func (db *MysqlDriver) InsertReturningContext(ctx context.Context, tableName string, post map[string]interface{}) (map[string]interface{}, error) {
+	ctx = withOperation(ctx, "InsertReturning", tableName)
+	s, _ := GetInsertSql(tableName, post, "mysql")
+	if db.mariaDBAtLeast(10, 5) {
+		s += " returning *"
+		rows, err := db.QueryContext(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		list, err := ReturnListFromResults(rows)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return map[string]interface{}{}, nil
+		}
+		return list[0], nil
+	}
+
+	tx, release, err := db.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer tx.Rollback()
+	exec, err := db.exec(ctx, tx, s)
+	if err != nil {
+		return nil, err
+	}
+	id, err := exec.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.query(ctx, tx, "select * from "+tableName+" where id = ? limit 1", id)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err = ClassifyError(tx.Commit()); err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return list[0], nil
+}
+
+func (db *MysqlDriver) UpdateReturning(tableName string, post map[string]interface{}, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.UpdateReturningContext(context.Background(), tableName, post, query)
+}
+
+func (db *MysqlDriver) UpdateReturningContext(ctx context.Context, tableName string, post map[string]interface{}, query map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx = withOperation(ctx, "UpdateReturning", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return nil, err
+	}
+	tx, release, err := db.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer tx.Rollback()
+	s := "select id from " + tableName + where + " for update"
+	rows, err := db.query(ctx, tx, s)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return []map[string]interface{}{}, ClassifyError(tx.Commit())
+	}
+	if db.MaxAffected > 0 && int64(len(list)) > db.MaxAffected {
+		return nil, ErrTooManyRows
+	}
+	ids := make([]interface{}, 0, len(list))
+	for _, row := range list {
+		ids = append(ids, row["id"])
+	}
+
+	s, _ = GetUpdateSQL(tableName, post, map[string]interface{}{}, "mysql")
+	s += " where id in " + placeholderList("mysql", len(ids))
+	if _, err = db.exec(ctx, tx, s, ids...); err != nil {
+		return nil, err
+	}
+	rows, err = db.query(ctx, tx, "select * from "+tableName+" where id in "+placeholderList("mysql", len(ids)), ids...)
+	if err != nil {
+		return nil, err
+	}
+	list, err = ReturnListFromResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	return list, ClassifyError(tx.Commit())
+}
+
+func (db *MysqlDriver) DeleteReturning(tableName string, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.DeleteReturningContext(context.Background(), tableName, query)
+}
+
+func (db *MysqlDriver) DeleteReturningContext(ctx context.Context, tableName string, query map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx = withOperation(ctx, "DeleteReturning", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return nil, err
+	}
+	if where == "" {
+		return []map[string]interface{}{}, nil
+	}
+	if db.mariaDBAtLeast(10, 0) {
+		s := "delete from " + tableName + where + " returning *"
+		return queryLimited(ctx, db.DB, db.beginTx, db.query, s, db.MaxAffected)
+	}
+
+	tx, release, err := db.beginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer tx.Rollback()
+	s := "select * from " + tableName + where + " for update"
+	rows, err := db.query(ctx, tx, s)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return list, ClassifyError(tx.Commit())
+	}
+	if db.MaxAffected > 0 && int64(len(list)) > db.MaxAffected {
+		return nil, ErrTooManyRows
+	}
+	s = "delete from " + tableName + where
+	if _, err = db.exec(ctx, tx, s); err != nil {
+		return nil, err
+	}
+	return list, ClassifyError(tx.Commit())
+}
+
+func (db *MysqlDriver) GetKeysetPage(tableName string, query map[string]interface{}, orderBy string, cursor string, size int64) ([]map[string]interface{}, *KeysetPage, error) {
+	return db.GetKeysetPageContext(context.Background(), tableName, query, orderBy, cursor, size)
+}
+
+func (db *MysqlDriver) GetKeysetPageContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, cursor string, size int64) ([]map[string]interface{}, *KeysetPage, error) {
+	ctx = withOperation(ctx, "GetKeysetPage", tableName)
+	if size <= 0 {
+		size = 10
+	}
+	sql2, args, keys, backward, err := keysetSQL(query, orderBy, cursor, size, "mysql")
+	if err != nil {
+		return nil, nil, err
+	}
+	s := "select * from " + tableName + sql2
+	rows, err := db.QueryContext(ctx, s, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, page := keysetResult(list, keys, size, cursor, backward)
+	return list, page, nil
+}
+
+func (db *MysqlDriver) Paginate(tableName string, query map[string]interface{}, orderBy string, page, size int64, options *PaginateOptions) (*PageResult, error) {
+	return db.PaginateContext(context.Background(), tableName, query, orderBy, page, size, options)
+}
+
+func (db *MysqlDriver) PaginateContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, page, size int64, options *PaginateOptions) (*PageResult, error) {
+	ctx = withOperation(ctx, "Paginate", tableName)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	if !CheckOrderBy(orderBy) {
+		orderBy = ""
+	}
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	fetch := func(page, size int64, windowCount bool, extra int64) ([]map[string]interface{}, error) {
+		s := "select *"
+		if windowCount {
+			s += ", count(*) over() as " + windowTotalColumn
+		}
+		s += " from " + tableName + where
+		if orderBy != "" {
+			s += " order by " + orderBy
+		}
+		s += " limit ? offset ?"
+		rows, err := db.QueryContext(ctx, s, size+extra, (page-1)*size)
+		if err != nil {
+			return nil, err
+		}
+		return ReturnListFromResults(rows)
+	}
+	count := func() (int64, error) {
+		return db.CountContext(ctx, tableName, query)
+	}
+	return paginate(count, fetch, page, size, options)
+}
+
+// Cursor walks every row matching query in primary key order, reading batch rows
+// per statement so memory stays bounded. MySQL has no server-side cursors outside
+// stored procedures, so orderBy is not supported here and rows come in id order.
+func (db *MysqlDriver) Cursor(tableName string, query map[string]interface{}, orderBy string, batch int64, fn func(Row) error) error {
+	return db.CursorContext(context.Background(), tableName, query, orderBy, batch, fn)
+}
+
+func (db *MysqlDriver) CursorContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, batch int64, fn func(Row) error) error {
+	ctx = withOperation(ctx, "Cursor", tableName)
+	if orderBy != "" && orderBy != "id asc" {
+		return errors.New("DBDriver: mysql cursor only supports id order")
+	}
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return err
+	}
+	return walkById(db.queryWith(ctx), "mysql", tableName, where, batch, eachInBatch(fn))
+}
+
+// ChunkById calls fn with batches of up to size rows ordered by id. Return ErrStop from fn to stop early.
+func (db *MysqlDriver) ChunkById(tableName string, query map[string]interface{}, size int64, fn func([]Row) error) error {
+	return db.ChunkByIdContext(context.Background(), tableName, query, size, fn)
+}
+
+func (db *MysqlDriver) ChunkByIdContext(ctx context.Context, tableName string, query map[string]interface{}, size int64, fn func([]Row) error) error {
+	ctx = withOperation(ctx, "ChunkById", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return err
+	}
+	return walkById(db.queryWith(ctx), "mysql", tableName, where, size, fn)
+}
+
+func (db *MysqlDriver) BatchUpdate(tableName string, post map[string]interface{}, query map[string]interface{}, options *BatchOptions) (int64, error) {
+	return db.BatchUpdateContext(context.Background(), tableName, post, query, options)
+}
+
+func (db *MysqlDriver) BatchUpdateContext(ctx context.Context, tableName string, post map[string]interface{}, query map[string]interface{}, options *BatchOptions) (int64, error) {
+	ctx = withOperation(ctx, "BatchUpdate", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return 0, err
+	}
+	s, _ := GetUpdateSQL(tableName, post, map[string]interface{}{}, "mysql")
+	exec := func(s string, total int64, args ...interface{}) (int64, error) {
+		return execBatch(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected, total, args...)
+	}
+	return batchUpdateById(ctx, db.queryWith(UsePrimary(ctx)), exec, "mysql", tableName, where, s, options)
+}
+
+func (db *MysqlDriver) BatchDelete(tableName string, query map[string]interface{}, options *BatchOptions) (int64, error) {
+	return db.BatchDeleteContext(context.Background(), tableName, query, options)
+}
+
+func (db *MysqlDriver) BatchDeleteContext(ctx context.Context, tableName string, query map[string]interface{}, options *BatchOptions) (int64, error) {
+	ctx = withOperation(ctx, "BatchDelete", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return 0, err
+	}
+	if where == "" {
+		return 0, nil
+	}
+	return batchLoop(ctx, options, func(size, total int64) (int64, bool, error) {
+		s := "delete from " + tableName + where + " limit " + SqlQuote(size)
+		affected, err := execBatch(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected, total)
+		return affected, affected < size, err
+	})
+}
+
+func (db *MysqlDriver) Sum(tableName string, column string, query map[string]interface{}) (float64, error) {
+	return db.SumContext(context.Background(), tableName, column, query)
+}
+
+func (db *MysqlDriver) SumContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (float64, error) {
+	ctx = withOperation(ctx, "Sum", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	return aggregateFloat(db.queryWith(ctx), tableName, "sum", column, where)
+}
+
+func (db *MysqlDriver) Avg(tableName string, column string, query map[string]interface{}) (float64, error) {
+	return db.AvgContext(context.Background(), tableName, column, query)
+}
+
+func (db *MysqlDriver) AvgContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (float64, error) {
+	ctx = withOperation(ctx, "Avg", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	return aggregateFloat(db.queryWith(ctx), tableName, "avg", column, where)
+}
+
+func (db *MysqlDriver) Min(tableName string, column string, query map[string]interface{}) (interface{}, error) {
+	return db.MinContext(context.Background(), tableName, column, query)
+}
+
+func (db *MysqlDriver) MinContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (interface{}, error) {
+	ctx = withOperation(ctx, "Min", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateValue(db.queryWith(ctx), tableName, "min", column, where)
+}
+
+func (db *MysqlDriver) Max(tableName string, column string, query map[string]interface{}) (interface{}, error) {
+	return db.MaxContext(context.Background(), tableName, column, query)
+}
+
+func (db *MysqlDriver) MaxContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (interface{}, error) {
+	ctx = withOperation(ctx, "Max", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateValue(db.queryWith(ctx), tableName, "max", column, where)
+}
+
+func (db *MysqlDriver) CountDistinct(tableName string, column string, query map[string]interface{}) (int64, error) {
+	return db.CountDistinctContext(context.Background(), tableName, column, query)
+}
+
+func (db *MysqlDriver) CountDistinctContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "CountDistinct", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	return countDistinct(db.queryWith(ctx), tableName, column, where)
+}
+
+func (db *MysqlDriver) Pluck(tableName string, column string, query map[string]interface{}, orderBy string) ([]interface{}, error) {
+	return db.PluckContext(context.Background(), tableName, column, query, orderBy)
+}
+
+func (db *MysqlDriver) PluckContext(ctx context.Context, tableName string, column string, query map[string]interface{}, orderBy string) ([]interface{}, error) {
+	ctx = withOperation(ctx, "Pluck", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return pluck(db.queryWith(ctx), tableName, column, where, orderBy)
+}
+
+func (db *MysqlDriver) GroupBy(tableName string, columns []string, aggregates []Aggregate, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.GroupByContext(context.Background(), tableName, columns, aggregates, query)
+}
+
+func (db *MysqlDriver) GroupByContext(ctx context.Context, tableName string, columns []string, aggregates []Aggregate, query map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx = withOperation(ctx, "GroupBy", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(db.queryWith(ctx), tableName, columns, aggregates, where)
+}
+
+func (db *MysqlDriver) GroupCount(tableName string, column string, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.GroupCountContext(context.Background(), tableName, column, query)
+}
+
+func (db *MysqlDriver) GroupCountContext(ctx context.Context, tableName string, column string, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.GroupByContext(ctx, tableName, []string{column}, []Aggregate{{Func: "count", As: "count"}}, query)
+}
+
+func (db *MysqlDriver) FindOneJoin(sel *Select, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	return db.FindOneJoinContext(context.Background(), sel, query, orderBy)
+}
+
+func (db *MysqlDriver) FindOneJoinContext(ctx context.Context, sel *Select, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "FindOneJoin", sel.Table)
+	s, err := GetSelectSQL(sel, query, "mysql")
+	if err != nil {
+		return nil, err
+	}
+	if CheckOrderBy(orderBy) {
+		s += " order by " + orderBy
+	}
+	return db.QueryContext(ctx, s+" limit 1")
+}
+
+func (db *MysqlDriver) GetListJoin(sel *Select, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	return db.GetListJoinContext(context.Background(), sel, query, orderBy)
+}
+
+func (db *MysqlDriver) GetListJoinContext(ctx context.Context, sel *Select, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "GetListJoin", sel.Table)
+	s, err := GetSelectSQL(sel, query, "mysql")
+	if err != nil {
+		return nil, err
+	}
+	if CheckOrderBy(orderBy) {
+		s += " order by " + orderBy
+	}
+	return db.QueryContext(ctx, s)
+}
+
+func (db *MysqlDriver) CountJoin(sel *Select, query map[string]interface{}) (int64, error) {
+	return db.CountJoinContext(context.Background(), sel, query)
+}
+
+func (db *MysqlDriver) CountJoinContext(ctx context.Context, sel *Select, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "CountJoin", sel.Table)
+	var count int64
+	s, err := GetSelectCountSQL(sel, query, "mysql")
+	if err != nil {
+		return 0, err
+	}
+	err = queryScalar(db.queryWith(ctx), s, &count)
+	return count, err
+}
+
+func (db *MysqlDriver) GetPageJoin(sel *Select, query map[string]interface{}, orderBy string, page, size int64) (*sql.Rows, *Page, error) {
+	return db.GetPageJoinContext(context.Background(), sel, query, orderBy, page, size)
+}
+
+func (db *MysqlDriver) GetPageJoinContext(ctx context.Context, sel *Select, query map[string]interface{}, orderBy string, page, size int64) (*sql.Rows, *Page, error) {
+	ctx = withOperation(ctx, "GetPageJoin", sel.Table)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	total, err := db.CountJoinContext(ctx, sel, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := NewPage(page, size, total)
+	s, err := GetSelectSQL(sel, query, "mysql")
+	if err != nil {
+		return nil, nil, err
+	}
+	if CheckOrderBy(orderBy) {
+		s += " order by " + orderBy
+	}
+	rows, err := db.QueryContext(ctx, s+" limit ? offset ?", p.Size, (p.Page-1)*p.Size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, p, nil
+}
+
+func (db *MysqlDriver) Relate(tableName string, relation Relation) error {
+	relations, err := addRelation(db.Relations, tableName, relation)
+	if err != nil {
+		return err
+	}
+	db.Relations = relations
+	return nil
+}
+
+func (db *MysqlDriver) GetListPreload(tableName string, query map[string]interface{}, orderBy string, with ...string) ([]map[string]interface{}, error) {
+	return db.GetListPreloadContext(context.Background(), tableName, query, orderBy, with...)
+}
+
+func (db *MysqlDriver) GetListPreloadContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, with ...string) ([]map[string]interface{}, error) {
+	ctx = withOperation(ctx, "GetListPreload", tableName)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	rows, err := db.GetListContext(ctx, tableName, query, orderBy)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	return list, preload(db.queryWith(ctx), "mysql", db.Relations, tableName, list, with)
+}
+
+func (db *MysqlDriver) GetPagePreload(tableName string, query map[string]interface{}, orderBy string, page, size int64, with ...string) ([]map[string]interface{}, *Page, error) {
+	return db.GetPagePreloadContext(context.Background(), tableName, query, orderBy, page, size, with...)
+}
+
+func (db *MysqlDriver) GetPagePreloadContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, page, size int64, with ...string) ([]map[string]interface{}, *Page, error) {
+	ctx = withOperation(ctx, "GetPagePreload", tableName)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	rows, p, err := db.GetPageContext(ctx, tableName, query, orderBy, page, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return list, p, preload(db.queryWith(ctx), "mysql", db.Relations, tableName, list, with)
+}
+
+// Increment adds by to column in a single update, so concurrent callers do not race.
+func (db *MysqlDriver) Increment(tableName string, column string, by float64, query map[string]interface{}) (int64, error) {
+	return db.IncrementContext(context.Background(), tableName, column, by, query)
+}
+
+func (db *MysqlDriver) IncrementContext(ctx context.Context, tableName string, column string, by float64, query map[string]interface{}) (int64, error) {
+	expr, err := incrementExpr(column, by)
+	if err != nil {
+		return 0, err
+	}
+	return db.UpdateContext(ctx, tableName, map[string]interface{}{column: expr}, query)
+}
+
+func (db *MysqlDriver) Decrement(tableName string, column string, by float64, query map[string]interface{}) (int64, error) {
+	return db.DecrementContext(context.Background(), tableName, column, by, query)
+}
+
+func (db *MysqlDriver) DecrementContext(ctx context.Context, tableName string, column string, by float64, query map[string]interface{}) (int64, error) {
+	return db.IncrementContext(ctx, tableName, column, -by, query)
+}
+
+// QueryNamed runs query with :name placeholders bound from a map or struct.
+func (db *MysqlDriver) QueryNamed(query string, arg interface{}) (*sql.Rows, error) {
+	return db.QueryNamedContext(context.Background(), query, arg)
+}
+
+func (db *MysqlDriver) QueryNamedContext(ctx context.Context, query string, arg interface{}) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "QueryNamed", "")
+	s, args, err := CompileNamed(query, "mysql", arg)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, s, args...)
+}
+
+func (db *MysqlDriver) ExecNamed(query string, arg interface{}) (sql.Result, error) {
+	return db.ExecNamedContext(context.Background(), query, arg)
+}
+
+func (db *MysqlDriver) ExecNamedContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	ctx = withOperation(ctx, "ExecNamed", "")
+	s, args, err := CompileNamed(query, "mysql", arg)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, s, args...)
+}
+
+// Health reports latency, server version, pool stats and whether the server is a
+// read only replica. Replication role needs the REPLICATION CLIENT privilege and
+// falls back to read_only without it.
+func (db *MysqlDriver) Health(ctx context.Context) *HealthStatus {
+	status := newHealthStatus(db.DriverName, db.DB.Stats(), db.Breaker)
+	return checkHealth(ctx, db.DB, status, func(ctx context.Context, status *HealthStatus) error {
+		var readOnly, superReadOnly int
+		err := db.DB.QueryRowContext(ctx, "select version(), @@global.read_only, @@global.super_read_only").Scan(&status.ServerVersion, &readOnly, &superReadOnly)
+		if err != nil {
+			err = db.DB.QueryRowContext(ctx, "select version(), @@global.read_only").Scan(&status.ServerVersion, &readOnly)
+			if err != nil {
+				return err
+			}
+		}
+		status.ReadOnly = readOnly == 1 || superReadOnly == 1
+		status.Role = "primary"
+		if status.ReadOnly {
+			status.Role = "replica"
+		}
+		for _, s := range []string{"show replica status", "show slave status"} {
+			rows, err := db.DB.QueryContext(ctx, s)
+			if err != nil {
+				continue
+			}
+			if rows.Next() {
+				status.Role = "replica"
+			}
+			rows.Close()
+			break
+		}
+		return nil
+	})
+}
+
+// AddReplica opens a read replica pool. Plain selects through Query and the map based
+// readers then go to the replicas, picked by Balancer; writes and transactions stay
+// on the primary. Pass a ctx from UsePrimary to QueryContext or an XContext method
+// to read from the primary. Paged and preloading reads keep all their statements
+// on one replica.
+func (db *MysqlDriver) AddReplica(host string, port int, user, password, dbname string) error {
+	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&autocommit=true", user, password, host, port, dbname, "utf8")
+	replica, err := openPool(db.DriverName, dataSourceName)
+	if err != nil {
+		return err
+	}
+	db.Replicas = append(db.Replicas, replica)
+	name := poolName(db.DriverName, dataSourceName)
+	db.replicaNames = append(db.replicaNames, name)
+	if db.Metrics != nil {
+		db.Metrics.RegisterPool(name, replica.Stats)
+	}
+	if db.Balancer == nil {
+		db.Balancer = &RoundRobin{}
+	}
+	return nil
+}
+
+func (db *MysqlDriver) SetBalancer(balancer Balancer) error {
+	db.Balancer = balancer
+	return nil
 }
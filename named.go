@@ -0,0 +1,117 @@
+package DBDriver
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// namedValues turns a map or struct into the values bound to :name placeholders.
+// Struct fields are named by their db tag, json tag or snake cased field name.
+func namedValues(arg interface{}) (map[string]interface{}, error) {
+	if m, ok := arg.(map[string]interface{}); ok {
+		return m, nil
+	}
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errors.New("DBDriver: named arguments must be a map or struct")
+	}
+	values := map[string]interface{}{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := columnName(field)
+		if name == "-" {
+			continue
+		}
+		values[name] = v.Field(i).Interface()
+		values[snakeCase(field.Name)] = v.Field(i).Interface()
+	}
+	return values, nil
+}
+
+func placeholder(driverName string, n int) string {
+	if driverName == "postgres" {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// placeholderList returns an in list of n placeholders numbered from 1.
+func placeholderList(driverName string, n int) string {
+	marks := make([]string, n)
+	for i := range marks {
+		marks[i] = placeholder(driverName, i+1)
+	}
+	return "(" + strings.Join(marks, ", ") + ")"
+}
+
+// CompileNamed rewrites :name placeholders to the driver's style and returns the
+// bound arguments in order. Slices are expanded for in lists; an empty slice is an
+// error, as no single rewrite is right for both x in () and x not in ().
+// Postgres :: casts, literals and comments are left alone.
+func CompileNamed(query string, driverName string, arg interface{}) (string, []interface{}, error) {
+	values, err := namedValues(arg)
+	if err != nil {
+		return "", nil, err
+	}
+	var b strings.Builder
+	args := make([]interface{}, 0, len(values))
+	for _, segment := range segmentSQL(query, driverName) {
+		if !segment.code {
+			b.WriteString(segment.text)
+			continue
+		}
+		text := segment.text
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+			if c != ':' {
+				b.WriteByte(c)
+				continue
+			}
+			if i+1 < len(text) && text[i+1] == ':' {
+				b.WriteString("::")
+				i++
+				continue
+			}
+			j := i + 1
+			for j < len(text) && (text[j] == '_' || isLetter(text[j]) || (j > i+1 && isDigit(text[j]))) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte(c)
+				continue
+			}
+			name := text[i+1 : j]
+			value, ok := values[name]
+			if !ok {
+				return "", nil, errors.New("DBDriver: missing named argument " + name)
+			}
+			rv := reflect.ValueOf(value)
+			if value != nil && rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+				if rv.Len() == 0 {
+					return "", nil, errors.New("DBDriver: empty list for named argument " + name)
+				}
+				for k := 0; k < rv.Len(); k++ {
+					if k > 0 {
+						b.WriteString(", ")
+					}
+					args = append(args, rv.Index(k).Interface())
+					b.WriteString(placeholder(driverName, len(args)))
+				}
+			} else {
+				args = append(args, value)
+				b.WriteString(placeholder(driverName, len(args)))
+			}
+			i = j - 1
+		}
+	}
+	return b.String(), args, nil
+}
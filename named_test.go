@@ -0,0 +1,52 @@
+package DBDriver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompileNamed(t *testing.T) {
+	type user struct {
+		ID       int64 `db:"id"`
+		UserName string
+		secret   string
+	}
+	tests := []struct {
+		name       string
+		query      string
+		driverName string
+		arg        interface{}
+		want       string
+		wantArgs   []interface{}
+		wantErr    bool
+	}{
+		{"mysql", "select * from t where a = :a and b = :b", "mysql", map[string]interface{}{"a": 1, "b": "x"}, "select * from t where a = ? and b = ?", []interface{}{1, "x"}, false},
+		{"postgres", "select * from t where a = :a and b = :a", "postgres", map[string]interface{}{"a": 1}, "select * from t where a = $1 and b = $2", []interface{}{1, 1}, false},
+		{"cast", "select :a::int", "postgres", map[string]interface{}{"a": "1"}, "select $1::int", []interface{}{"1"}, false},
+		{"literal and comment", "select ':a' -- :a\n, :a", "postgres", map[string]interface{}{"a": 1}, "select ':a' -- :a\n, $1", []interface{}{1}, false},
+		{"escape string", `select E'\':a', :a`, "postgres", map[string]interface{}{"a": 1}, `select E'\':a', $1`, []interface{}{1}, false},
+		{"in list", "a in (:ids)", "postgres", map[string]interface{}{"ids": []int{1, 2, 3}}, "a in ($1, $2, $3)", []interface{}{1, 2, 3}, false},
+		{"bytes", "a = :b", "mysql", map[string]interface{}{"b": []byte("x")}, "a = ?", []interface{}{[]byte("x")}, false},
+		{"lone colon", "a : b", "mysql", map[string]interface{}{}, "a : b", []interface{}{}, false},
+		{"struct", "id = :id and name = :user_name", "mysql", user{ID: 7, UserName: "u"}, "id = ? and name = ?", []interface{}{int64(7), "u"}, false},
+		{"struct pointer", "id = :id", "postgres", &user{ID: 7}, "id = $1", []interface{}{int64(7)}, false},
+		{"unexported field", "a = :secret", "mysql", user{}, "", nil, true},
+		{"missing", "a = :b", "mysql", map[string]interface{}{}, "", nil, true},
+		{"empty list", "a in (:ids)", "mysql", map[string]interface{}{"ids": []int{}}, "", nil, true},
+		{"not a map or struct", "a = :a", "mysql", 1, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := CompileNamed(tt.query, tt.driverName, tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileNamed(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("CompileNamed(%q) = %q, %v, want %q, %v", tt.query, got, args, tt.want, tt.wantArgs)
+			}
+		})
+	}
+}
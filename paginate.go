@@ -0,0 +1,121 @@
+package DBDriver
+
+const (
+	DefaultPageSize int64 = 10
+	MaxPageSize     int64 = 1000
+)
+
+type PaginateOptions struct {
+	// SkipTotal skips the count query; Total and Last are left at 0
+	// and Next is only set when another page exists.
+	SkipTotal bool
+	// WindowCount reads the total with count(*) over() in the page query
+	// instead of running a separate Count.
+	WindowCount bool
+	MaxSize     int64
+}
+
+type PageResult struct {
+	Items []map[string]interface{} `json:"items"`
+	Page  *Page                    `json:"page"`
+}
+
+// Scan decodes the items into dest, a pointer to a slice of structs.
+func (r *PageResult) Scan(dest interface{}) error {
+	return MapsToStructs(r.Items, dest)
+}
+
+const windowTotalColumn = "dbdriver_total_"
+
+// NewPage returns the page metadata for total rows, clamping page into [1, last].
+func NewPage(page, size, total int64) *Page {
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	last := (total + size - 1) / size
+	if last < 1 {
+		last = 1
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > last {
+		page = last
+	}
+	prev := page - 1
+	if prev < 1 {
+		prev = 1
+	}
+	next := page + 1
+	if next > last {
+		next = last
+	}
+	return &Page{First: 1, Prev: prev, Page: page, Next: next, Last: last, Size: size, Total: total}
+}
+
+func clampPage(page, size int64, options *PaginateOptions) (int64, int64) {
+	maxSize := MaxPageSize
+	if options != nil && options.MaxSize > 0 {
+		maxSize = options.MaxSize
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	if size > maxSize {
+		size = maxSize
+	}
+	return page, size
+}
+
+type pageQueryFunc func(page, size int64, windowCount bool, extra int64) ([]map[string]interface{}, error)
+
+func paginate(count func() (int64, error), fetch pageQueryFunc, page, size int64, options *PaginateOptions) (*PageResult, error) {
+	if options == nil {
+		options = &PaginateOptions{}
+	}
+	page, size = clampPage(page, size, options)
+
+	if options.SkipTotal {
+		list, err := fetch(page, size, false, 1)
+		if err != nil {
+			return nil, err
+		}
+		p := &Page{First: 1, Prev: page - 1, Page: page, Next: 0, Size: size}
+		if p.Prev < 1 {
+			p.Prev = 1
+		}
+		if int64(len(list)) > size {
+			list = list[:size]
+			p.Next = page + 1
+		}
+		return &PageResult{Items: list, Page: p}, nil
+	}
+
+	if options.WindowCount {
+		list, err := fetch(page, size, true, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) > 0 {
+			total, _ := toInt64(list[0][windowTotalColumn])
+			for _, row := range list {
+				delete(row, windowTotalColumn)
+			}
+			return &PageResult{Items: list, Page: NewPage(page, size, total)}, nil
+		}
+	}
+
+	total, err := count()
+	if err != nil {
+		return nil, err
+	}
+	p := NewPage(page, size, total)
+	list, err := fetch(p.Page, size, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &PageResult{Items: list, Page: p}, nil
+}
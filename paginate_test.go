@@ -0,0 +1,49 @@
+package DBDriver
+
+import "testing"
+
+func TestNewPage(t *testing.T) {
+	tests := []struct {
+		page, size, total int64
+		want              Page
+	}{
+		{1, 10, 0, Page{First: 1, Prev: 1, Page: 1, Next: 1, Last: 1, Size: 10, Total: 0}},
+		{1, 10, 10, Page{First: 1, Prev: 1, Page: 1, Next: 1, Last: 1, Size: 10, Total: 10}},
+		{1, 10, 11, Page{First: 1, Prev: 1, Page: 1, Next: 2, Last: 2, Size: 10, Total: 11}},
+		{2, 10, 20, Page{First: 1, Prev: 1, Page: 2, Next: 2, Last: 2, Size: 10, Total: 20}},
+		{3, 10, 45, Page{First: 1, Prev: 2, Page: 3, Next: 4, Last: 5, Size: 10, Total: 45}},
+		{9, 10, 45, Page{First: 1, Prev: 4, Page: 5, Next: 5, Last: 5, Size: 10, Total: 45}},
+		{0, 10, 45, Page{First: 1, Prev: 1, Page: 1, Next: 2, Last: 5, Size: 10, Total: 45}},
+		{1, 0, 25, Page{First: 1, Prev: 1, Page: 1, Next: 2, Last: 3, Size: DefaultPageSize, Total: 25}},
+		{1, -5, 25, Page{First: 1, Prev: 1, Page: 1, Next: 2, Last: 3, Size: DefaultPageSize, Total: 25}},
+	}
+	for _, tt := range tests {
+		if got := NewPage(tt.page, tt.size, tt.total); *got != tt.want {
+			t.Errorf("NewPage(%d, %d, %d) = %+v, want %+v", tt.page, tt.size, tt.total, *got, tt.want)
+		}
+	}
+}
+
+func TestClampPage(t *testing.T) {
+	tests := []struct {
+		page, size         int64
+		options            *PaginateOptions
+		wantPage, wantSize int64
+	}{
+		{1, 20, nil, 1, 20},
+		{2, 20, nil, 2, 20},
+		{0, 20, nil, 1, 20},
+		{-3, 20, nil, 1, 20},
+		{1, 0, nil, 1, DefaultPageSize},
+		{1, -1, nil, 1, DefaultPageSize},
+		{1, MaxPageSize + 1, nil, 1, MaxPageSize},
+		{1, 500, &PaginateOptions{MaxSize: 100}, 1, 100},
+		{1, 50, &PaginateOptions{MaxSize: 100}, 1, 50},
+	}
+	for _, tt := range tests {
+		page, size := clampPage(tt.page, tt.size, tt.options)
+		if page != tt.wantPage || size != tt.wantSize {
+			t.Errorf("clampPage(%d, %d, %+v) = %d, %d, want %d, %d", tt.page, tt.size, tt.options, page, size, tt.wantPage, tt.wantSize)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package DBDriver
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	_ "github.com/lib/pq"
 	"log"
+	"log/slog"
 	"time"
 )
 
@@ -14,6 +17,23 @@ type PostgresDriver struct {
 	Show           bool
 	DB             *sql.DB
 	SQLTX          *sql.Tx
+	Safe           bool
+	MaxAffected    int64
+	Relations      map[string][]Relation
+	Rebind         bool
+	Logger         *slog.Logger
+	LogLevel       slog.Level
+	Redact         func([]interface{}) []interface{}
+	SlowLog        *SlowLog
+	Hooks          []Hook
+	Metrics        Metrics
+	Retry          *RetryPolicy
+	Breaker        *CircuitBreaker
+	Limiter        *Limiter
+	Replicas       []*sql.DB
+	Balancer       Balancer
+	replicaNames   []string
+	releaseTx      func()
 }
 
 func InitPostgreDriver(host string, port int, user, password, dbname string) *PostgresDriver {
@@ -44,6 +64,9 @@ func (db *PostgresDriver) Open() (err error) {
 }
 
 func (db *PostgresDriver) Close() error {
+	for _, replica := range db.Replicas {
+		replica.Close()
+	}
 	return db.DB.Close()
 }
 
@@ -57,29 +80,193 @@ func (db *PostgresDriver) HideSql() error {
 	return nil
 }
 
-func (db *PostgresDriver) Query(query string, args ...interface{}) (*sql.Rows, error) {
+// SetLogger sends every statement to logger at level; failed statements use error level.
+func (db *PostgresDriver) SetLogger(logger *slog.Logger, level slog.Level) error {
+	db.Logger = logger
+	db.LogLevel = level
+	return nil
+}
+
+func (db *PostgresDriver) SetSlowLog(slowLog *SlowLog) error {
+	db.SlowLog = slowLog
+	return nil
+}
+
+// SlowQueries returns the n statement fingerprints with the most total time, see SlowLog.Top.
+func (db *PostgresDriver) SlowQueries(n int) []QueryStats {
+	return db.SlowLog.Top(n)
+}
+
+func (db *PostgresDriver) statementLog() statementLog {
+	if db.Logger != nil {
+		return statementLog{Logger: db.Logger, Level: db.LogLevel, Redact: db.Redact}
+	}
 	if db.Show {
-		fmt.Println(query)
-		fmt.Println(args...)
+		return statementLog{Logger: showSqlLogger, Level: slog.LevelInfo, Redact: db.Redact}
+	}
+	return statementLog{}
+}
+
+func (db *PostgresDriver) query(ctx context.Context, conn sqlConn, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	retry := retryPolicyFor(db.Retry, ctx, conn, isReadStatement(query))
+	err := retry.do(ctx, func() error {
+		event := newQueryEvent(ctx, "Query", conn, query, args)
+		err := runHooks(ctx, db.Hooks, event, func(ctx context.Context) error {
+			var err error
+			start := time.Now()
+			rows, err = guardedQuery(ctx, db.Breaker, db.Limiter, conn, event.SQL, event.Args)
+			duration := time.Since(start)
+			db.statementLog().log(ctx, event.SQL, event.Args, duration, -1, err)
+			db.SlowLog.observe(ctx, db.Logger, event.SQL, db.Redact != nil, duration, err)
+			return ClassifyError(err)
+		})
+		observeStatement(db.Metrics, db.DriverName, db.DataSourceName, db.Breaker, db.Limiter, event)
+		return err
+	})
+	if err != nil && rows != nil {
+		rows.Close()
+		rows = nil
+	}
+	return rows, ClassifyError(err)
+}
+
+func (db *PostgresDriver) exec(ctx context.Context, conn sqlConn, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	retry := retryPolicyFor(db.Retry, ctx, conn, false)
+	err := retry.do(ctx, func() error {
+		event := newQueryEvent(ctx, "Exec", conn, query, args)
+		err := runHooks(ctx, db.Hooks, event, func(ctx context.Context) error {
+			var err error
+			start := time.Now()
+			result, err = guardedExec(ctx, db.Breaker, db.Limiter, conn, event.SQL, event.Args)
+			duration := time.Since(start)
+			if err == nil {
+				if n, e := result.RowsAffected(); e == nil {
+					event.RowsAffected = n
+				}
+			}
+			db.statementLog().log(ctx, event.SQL, event.Args, duration, event.RowsAffected, err)
+			db.SlowLog.observe(ctx, db.Logger, event.SQL, db.Redact != nil, duration, err)
+			return ClassifyError(err)
+		})
+		observeStatement(db.Metrics, db.DriverName, db.DataSourceName, db.Breaker, db.Limiter, event)
+		return err
+	})
+	if err != nil {
+		return nil, ClassifyError(err)
 	}
-	return db.DB.Query(query, args...)
+	return result, nil
+}
+
+func (db *PostgresDriver) queryWith(ctx context.Context) func(string, ...interface{}) (*sql.Rows, error) {
+	return func(query string, args ...interface{}) (*sql.Rows, error) {
+		return db.QueryContext(ctx, query, args...)
+	}
+}
+
+func (db *PostgresDriver) AddHook(hook Hook) error {
+	db.Hooks = append(db.Hooks, hook)
+	return nil
+}
+
+func (db *PostgresDriver) SetRetryPolicy(policy *RetryPolicy) error {
+	db.Retry = policy
+	return nil
+}
+
+// SetCircuitBreaker puts breaker in front of every statement, replacing any earlier
+// one; nil removes it. Its state is reported to the driver's Metrics when that
+// implements StateMetrics.
+func (db *PostgresDriver) SetCircuitBreaker(breaker *CircuitBreaker) error {
+	db.Breaker = breaker
+	return nil
+}
+
+// SetLimiter bounds the statements running at once, see Limiter. It replaces any
+// earlier limiter; nil removes it.
+func (db *PostgresDriver) SetLimiter(limiter *Limiter) error {
+	db.Limiter = limiter
+	return nil
+}
+
+// RunInTx runs fn in a new transaction and commits it when fn returns nil. With a
+// retry policy and a RetrySafe ctx the transaction is repeated on transient failures.
+func (db *PostgresDriver) RunInTx(ctx context.Context, fn func(*Tx) error) error {
+	return runInTx(ctx, db.Retry, db.beginTx, func(tx *sql.Tx) error {
+		return fn(&Tx{Tx: tx, driverName: db.DriverName, rebind: db.Rebind, query: db.query, exec: db.exec})
+	})
+}
+
+// beginTx begins a transaction behind the breaker and limiter, see beginTx.
+func (db *PostgresDriver) beginTx(ctx context.Context) (*sql.Tx, func(), error) {
+	return beginTx(ctx, db.Breaker, db.Limiter, db.DB)
+}
+
+// SetMetrics reports every statement and the primary and replica pools to metrics,
+// replacing any earlier Metrics; nil turns reporting off.
+func (db *PostgresDriver) SetMetrics(metrics Metrics) error {
+	db.Metrics = metrics
+	if metrics != nil {
+		registerPools(metrics, db.DriverName, db.DataSourceName, db.DB, db.Replicas, db.replicaNames)
+	}
+	return nil
+}
+
+func (db *PostgresDriver) Stats() sql.DBStats {
+	return db.DB.Stats()
+}
+
+func (db *PostgresDriver) SetSafe(safe bool) error {
+	db.Safe = safe
+	return nil
+}
+
+func (db *PostgresDriver) SetMaxAffected(max int64) error {
+	db.MaxAffected = max
+	return nil
+}
+
+// SetRebind lets raw statements use ? placeholders on every driver, see RebindSQL.
+func (db *PostgresDriver) SetRebind(rebind bool) error {
+	db.Rebind = rebind
+	return nil
+}
+
+func (db *PostgresDriver) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *PostgresDriver) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if db.Rebind {
+		query = RebindSQL(query, db.DriverName)
+	}
+	return db.query(ctx, readConn(ctx, db.DB, db.Replicas, db.Balancer, query), query, args...)
 }
 
 func (db *PostgresDriver) Exec(query string, args ...interface{}) (sql.Result, error) {
-	if db.Show {
-		fmt.Println(query)
-		fmt.Println(args...)
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *PostgresDriver) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if db.Rebind {
+		query = RebindSQL(query, db.DriverName)
 	}
-	return db.DB.Exec(query, args...)
+	return db.exec(ctx, db.DB, query, args...)
 }
 
 func (db *PostgresDriver) QueryMap(tableName string, query map[string]interface{}) (*sql.Rows, error) {
+	return db.QueryMapContext(context.Background(), tableName, query)
+}
+
+func (db *PostgresDriver) QueryMapContext(ctx context.Context, tableName string, query map[string]interface{}) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "QueryMap", tableName)
 	s := "select * from \"" + tableName + "\" "
-	where, _ := WhereFromQuery(query)
-	if db.Show {
-		fmt.Println(s + where)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
 	}
-	rows, err := db.Query(s + where)
+	rows, err := db.QueryContext(ctx, s+where)
 	if err != nil {
 		return nil, err
 	}
@@ -88,12 +275,13 @@ func (db *PostgresDriver) QueryMap(tableName string, query map[string]interface{
 }
 
 func (db *PostgresDriver) FindById(tableName string, id int64) (*sql.Rows, error) {
+	return db.FindByIdContext(context.Background(), tableName, id)
+}
+
+func (db *PostgresDriver) FindByIdContext(ctx context.Context, tableName string, id int64) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "FindById", tableName)
 	s := "select * from \"" + tableName + "\" where id = $1 limit 1 "
-	if db.Show {
-		fmt.Println(s)
-		fmt.Println(id)
-	}
-	rows, err := db.Query(s, id)
+	rows, err := db.QueryContext(ctx, s, id)
 	if err != nil {
 
 		return nil, err
@@ -102,15 +290,23 @@ func (db *PostgresDriver) FindById(tableName string, id int64) (*sql.Rows, error
 }
 
 func (db *PostgresDriver) FindOne(tableName string, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	return db.FindOneContext(context.Background(), tableName, query, orderBy)
+}
+
+func (db *PostgresDriver) FindOneContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "FindOne", tableName)
 	s := "select * from \"" + tableName + "\""
 	if !CheckOrderBy(orderBy) {
 		orderBy = ""
 	}
-	where, _ := WhereFromQuery(query)
-	if db.Show {
-		fmt.Println(s + where)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if orderBy != "" {
+		where += " order by " + orderBy
 	}
-	rows, err := db.DB.Query(s + where)
+	rows, err := db.QueryContext(ctx, s+where)
 	if err != nil {
 		return nil, err
 	}
@@ -118,15 +314,23 @@ func (db *PostgresDriver) FindOne(tableName string, query map[string]interface{}
 }
 
 func (db *PostgresDriver) GetList(tableName string, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	return db.GetListContext(context.Background(), tableName, query, orderBy)
+}
+
+func (db *PostgresDriver) GetListContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "GetList", tableName)
 	s := "select * from \"" + tableName + "\""
 	if !CheckOrderBy(orderBy) {
 		orderBy = ""
 	}
-	where, _ := WhereFromQuery(query)
-	if db.Show {
-		fmt.Println(s + where)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if orderBy != "" {
+		where += " order by " + orderBy
 	}
-	rows, err := db.DB.Query(s + where)
+	rows, err := db.QueryContext(ctx, s+where)
 	if err != nil {
 		return nil, err
 	}
@@ -134,60 +338,69 @@ func (db *PostgresDriver) GetList(tableName string, query map[string]interface{}
 }
 
 func (db *PostgresDriver) GetPage(tableName string, query map[string]interface{}, orderBy string, page, size int64) (*sql.Rows, *Page, error) {
-	var total, last, prev, next int64
-	total, _ = db.Count(tableName, query)
-	last = total/size + 1
-	prev = 1
-	if page > 2 {
-		prev = page - 1
-	} else {
-		page = 1
-	}
-	next = last
-	if page < last-1 {
-		next = page + 1
+	return db.GetPageContext(context.Background(), tableName, query, orderBy, page, size)
+}
+
+func (db *PostgresDriver) GetPageContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, page, size int64) (*sql.Rows, *Page, error) {
+	ctx = withOperation(ctx, "GetPage", tableName)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	total, err := db.CountContext(ctx, tableName, query)
+	if err != nil {
+		return nil, nil, err
 	}
-	offset := (page - 1) * size
+	p := NewPage(page, size, total)
+	offset := (p.Page - 1) * p.Size
 	s := "select * from \"" + tableName + "\""
 	if !CheckOrderBy(orderBy) {
 		orderBy = ""
 	}
-	where, _ := WhereFromQuery(query)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, nil, err
+	}
 	sql2 := s + where
 	if orderBy != "" {
 		sql2 += " order by " + orderBy
 	}
 	sql2 += " limit $1 offset $2"
-	if db.Show {
-		fmt.Println(sql2)
-		fmt.Println(size,offset)
-	}
-	rows, err := db.DB.Query(sql2, size, offset)
+	rows, err := db.QueryContext(ctx, sql2, p.Size, offset)
 	if err != nil {
 		return nil, nil, err
 	}
-	return rows, &Page{First: 1, Prev: prev, Page: page, Next: next, Last: last, Size: size, Total: total}, nil
+	return rows, p, nil
 }
 
 func (db *PostgresDriver) Count(tableName string, query map[string]interface{}) (int64, error) {
+	return db.CountContext(context.Background(), tableName, query)
+}
+
+func (db *PostgresDriver) CountContext(ctx context.Context, tableName string, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "Count", tableName)
 	var count int64 = 0
 	s := "select count(1) as number from \"" + tableName+ "\""
-	where, _ := WhereFromQuery(query)
-	if db.Show {
-		fmt.Println(s + where)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return 0, err
 	}
-	rows, err := db.DB.Query(s + where)
+	rows, err := db.QueryContext(ctx, s+where)
 	if err != nil {
 		return 0, err
 	}
 	defer rows.Close()
-	rows.Next()
-	_ = rows.Scan(&count)
-	return count, nil
+	if rows.Next() {
+		if err = rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, rows.Err()
 }
 
 func (db *PostgresDriver) Exists(tableName string, query map[string]interface{}) bool {
-	c, err := db.Count(tableName, query)
+	return db.ExistsContext(context.Background(), tableName, query)
+}
+
+func (db *PostgresDriver) ExistsContext(ctx context.Context, tableName string, query map[string]interface{}) bool {
+	c, err := db.CountContext(ctx, tableName, query)
 	if err != nil {
 		return false
 	}
@@ -195,13 +408,15 @@ func (db *PostgresDriver) Exists(tableName string, query map[string]interface{})
 }
 
 func (db *PostgresDriver) Insert(tableName string, post map[string]interface{}) (int64, error) {
+	return db.InsertContext(context.Background(), tableName, post)
+}
+
+func (db *PostgresDriver) InsertContext(ctx context.Context, tableName string, post map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "Insert", tableName)
 	var newId int64
 	s, _ := GetInsertSql(tableName, post,"postgres")
-	if db.Show {
-		fmt.Println(s)
-	}
 
-	err := db.DB.QueryRow(s + "  returning id").Scan(&newId)
+	err := queryScalar(db.queryWith(ctx), s+" returning id", &newId)
 	if err != nil {
 		return 0, err
 	}
@@ -209,52 +424,80 @@ func (db *PostgresDriver) Insert(tableName string, post map[string]interface{})
 }
 
 func (db *PostgresDriver) Update(tableName string, post map[string]interface{}, query map[string]interface{}) (int64, error) {
-	s, _ := GetUpdateSQL(tableName, post, query,"postgres")
-	if db.Show {
-		fmt.Println(s)
-	}
-	exec, err := db.DB.Exec(s)
-	if err != nil {
+	return db.UpdateContext(context.Background(), tableName, post, query)
+}
+
+func (db *PostgresDriver) UpdateContext(ctx context.Context, tableName string, post map[string]interface{}, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "Update", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
 		return 0, err
 	}
-	return exec.RowsAffected()
+	s, _ := GetUpdateSQL(tableName, post, query, "postgres")
+	return execLimited(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected)
+}
+
+func (db *PostgresDriver) UpdateAll(tableName string, post map[string]interface{}) (int64, error) {
+	return db.UpdateAllContext(context.Background(), tableName, post)
+}
+
+func (db *PostgresDriver) UpdateAllContext(ctx context.Context, tableName string, post map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "UpdateAll", tableName)
+	s, _ := GetUpdateSQL(tableName, post, map[string]interface{}{}, "postgres")
+	return execLimited(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected)
 }
 
 func (db *PostgresDriver) Save(tableName string, post map[string]interface{}) (int64, error) {
+	return db.SaveContext(context.Background(), tableName, post)
+}
+
+func (db *PostgresDriver) SaveContext(ctx context.Context, tableName string, post map[string]interface{}) (int64, error) {
 	id, ok := post["id"]
 	if ok {
 		delete(post, "id")
-		return db.Update(tableName, post, map[string]interface{}{"id": id})
+		return db.UpdateContext(ctx, tableName, post, map[string]interface{}{"id": id})
 	} else {
-		return db.Insert(tableName, post)
+		return db.InsertContext(ctx, tableName, post)
 	}
 }
 
 func (db *PostgresDriver) Delete(tableName string, query map[string]interface{}) (int64, error) {
-	where, _ := WhereFromQuery(query)
+	return db.DeleteContext(context.Background(), tableName, query)
+}
+
+func (db *PostgresDriver) DeleteContext(ctx context.Context, tableName string, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "Delete", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return 0, err
+	}
 	if where != "" {
 		s := "delete from \"" + tableName + "\" " + where
-		if db.Show {
-			fmt.Println(s)
-		}
-		exec, err := db.DB.Exec(s)
-		if err != nil {
-			return 0, err
-		}
-		return exec.RowsAffected()
+		return execLimited(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected)
 	} else {
 		return 0, nil
 	}
 }
 
+func (db *PostgresDriver) DeleteAll(tableName string) (int64, error) {
+	return db.DeleteAllContext(context.Background(), tableName)
+}
+
+func (db *PostgresDriver) DeleteAllContext(ctx context.Context, tableName string) (int64, error) {
+	ctx = withOperation(ctx, "DeleteAll", tableName)
+	s := "delete from \"" + tableName + "\""
+	return execLimited(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected)
+}
+
 func (db *PostgresDriver) DeleteById(tableName string, id int64) (int64, error) {
+	return db.DeleteByIdContext(context.Background(), tableName, id)
+}
+
+func (db *PostgresDriver) DeleteByIdContext(ctx context.Context, tableName string, id int64) (int64, error) {
+	ctx = withOperation(ctx, "DeleteById", tableName)
 	if id != 0 {
 		s := "delete from \"" + tableName + "\" where id = $1"
-		if db.Show {
-			fmt.Println(s)
-			fmt.Println(id)
-		}
-		exec, err := db.DB.Exec(s, id)
+		exec, err := db.ExecContext(ctx, s, id)
 		if err != nil {
 			return 0, err
 		}
@@ -269,7 +512,7 @@ func (db *PostgresDriver) Begin() error {
 	if err != nil {
 		return nil
 	}
-	db.SQLTX, err = db.DB.Begin()
+	db.SQLTX, db.releaseTx, err = db.beginTx(context.Background())
 	if err != nil {
 		return err
 	}
@@ -277,17 +520,575 @@ func (db *PostgresDriver) Begin() error {
 }
 
 func (db *PostgresDriver) RollBack() error {
+	defer db.endTx()
 	return db.SQLTX.Rollback()
 }
 
 func (db *PostgresDriver) Commit() error {
-	return db.SQLTX.Commit()
+	defer db.endTx()
+	return ClassifyError(db.SQLTX.Commit())
+}
+
+// endTx gives back the limiter slot held by the transaction Begin started.
+func (db *PostgresDriver) endTx() {
+	if db.releaseTx != nil {
+		db.releaseTx()
+		db.releaseTx = nil
+	}
 }
 
 func (db *PostgresDriver) QueryTX(query string, args ...interface{}) (*sql.Rows, error) {
-	return db.SQLTX.Query(query, args...)
+	ctx := withOperation(context.Background(), "QueryTX", "")
+	if db.Rebind {
+		query = RebindSQL(query, db.DriverName)
+	}
+	return db.query(ctx, db.SQLTX, query, args...)
 }
 
 func (db *PostgresDriver) ExecTX(query string, args ...interface{}) (sql.Result, error) {
-	return db.SQLTX.Exec(query, args...)
+	ctx := withOperation(context.Background(), "ExecTX", "")
+	if db.Rebind {
+		query = RebindSQL(query, db.DriverName)
+	}
+	return db.exec(ctx, db.SQLTX, query, args...)
+}
+
+func (db *PostgresDriver) InsertReturning(tableName string, post map[string]interface{}) (map[string]interface{}, error) {
+	return db.InsertReturningContext(context.Background(), tableName, post)
+}
+
+func (db *PostgresDriver) InsertReturningContext(ctx context.Context, tableName string, post map[string]interface{}) (map[string]interface{}, error) {
+	ctx = withOperation(ctx, "InsertReturning", tableName)
+	s, _ := GetInsertSql(tableName, post, "postgres")
+	s += " returning *"
+	rows, err := db.QueryContext(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	return list[0], nil
+}
+
+func (db *PostgresDriver) UpdateReturning(tableName string, post map[string]interface{}, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.UpdateReturningContext(context.Background(), tableName, post, query)
+}
+
+func (db *PostgresDriver) UpdateReturningContext(ctx context.Context, tableName string, post map[string]interface{}, query map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx = withOperation(ctx, "UpdateReturning", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return nil, err
+	}
+	s, _ := GetUpdateSQL(tableName, post, query, "postgres")
+	return queryLimited(ctx, db.DB, db.beginTx, db.query, s+" returning *", db.MaxAffected)
+}
+
+func (db *PostgresDriver) DeleteReturning(tableName string, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.DeleteReturningContext(context.Background(), tableName, query)
+}
+
+func (db *PostgresDriver) DeleteReturningContext(ctx context.Context, tableName string, query map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx = withOperation(ctx, "DeleteReturning", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return nil, err
+	}
+	if where == "" {
+		return []map[string]interface{}{}, nil
+	}
+	s := "delete from \"" + tableName + "\" " + where + " returning *"
+	return queryLimited(ctx, db.DB, db.beginTx, db.query, s, db.MaxAffected)
+}
+
+func (db *PostgresDriver) GetKeysetPage(tableName string, query map[string]interface{}, orderBy string, cursor string, size int64) ([]map[string]interface{}, *KeysetPage, error) {
+	return db.GetKeysetPageContext(context.Background(), tableName, query, orderBy, cursor, size)
+}
+
+func (db *PostgresDriver) GetKeysetPageContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, cursor string, size int64) ([]map[string]interface{}, *KeysetPage, error) {
+	ctx = withOperation(ctx, "GetKeysetPage", tableName)
+	if size <= 0 {
+		size = 10
+	}
+	sql2, args, keys, backward, err := keysetSQL(query, orderBy, cursor, size, "postgres")
+	if err != nil {
+		return nil, nil, err
+	}
+	s := "select * from " + "\"" + tableName + "\"" + sql2
+	rows, err := db.QueryContext(ctx, s, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, page := keysetResult(list, keys, size, cursor, backward)
+	return list, page, nil
+}
+
+func (db *PostgresDriver) Paginate(tableName string, query map[string]interface{}, orderBy string, page, size int64, options *PaginateOptions) (*PageResult, error) {
+	return db.PaginateContext(context.Background(), tableName, query, orderBy, page, size, options)
+}
+
+func (db *PostgresDriver) PaginateContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, page, size int64, options *PaginateOptions) (*PageResult, error) {
+	ctx = withOperation(ctx, "Paginate", tableName)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	if !CheckOrderBy(orderBy) {
+		orderBy = ""
+	}
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	fetch := func(page, size int64, windowCount bool, extra int64) ([]map[string]interface{}, error) {
+		s := "select *"
+		if windowCount {
+			s += ", count(*) over() as " + windowTotalColumn
+		}
+		s += " from " + "\"" + tableName + "\"" + where
+		if orderBy != "" {
+			s += " order by " + orderBy
+		}
+		s += " limit $1 offset $2"
+		rows, err := db.QueryContext(ctx, s, size+extra, (page-1)*size)
+		if err != nil {
+			return nil, err
+		}
+		return ReturnListFromResults(rows)
+	}
+	count := func() (int64, error) {
+		return db.CountContext(ctx, tableName, query)
+	}
+	return paginate(count, fetch, page, size, options)
+}
+
+// Cursor walks every row matching query with DECLARE ... CURSOR inside its own
+// transaction, fetching batch rows at a time so memory stays bounded.
+func (db *PostgresDriver) Cursor(tableName string, query map[string]interface{}, orderBy string, batch int64, fn func(Row) error) error {
+	return db.CursorContext(context.Background(), tableName, query, orderBy, batch, fn)
+}
+
+func (db *PostgresDriver) CursorContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, batch int64, fn func(Row) error) error {
+	ctx = withOperation(ctx, "Cursor", tableName)
+	if batch <= 0 {
+		batch = DefaultCursorBatch
+	}
+	if !CheckOrderBy(orderBy) {
+		orderBy = ""
+	}
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return err
+	}
+	s := "select * from \"" + tableName + "\"" + where
+	if orderBy != "" {
+		s += " order by " + orderBy
+	}
+	s = "declare dbdriver_cursor no scroll cursor for " + s
+	tx, release, err := db.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	defer tx.Rollback()
+	if _, err = db.exec(ctx, tx, s); err != nil {
+		return err
+	}
+	fetch := "fetch forward " + SqlQuote(batch) + " from dbdriver_cursor"
+	for {
+		rows, err := db.query(ctx, tx, fetch)
+		if err != nil {
+			return err
+		}
+		n := 0
+		stopped := false
+		err = Each(rows, func(row Row) error {
+			n++
+			err := fn(row)
+			if errors.Is(err, ErrStop) {
+				stopped = true
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if stopped || int64(n) < batch {
+			break
+		}
+	}
+	if _, err = db.exec(ctx, tx, "close dbdriver_cursor"); err != nil {
+		return err
+	}
+	return ClassifyError(tx.Commit())
+}
+
+// ChunkById calls fn with batches of up to size rows ordered by id. Return ErrStop from fn to stop early.
+func (db *PostgresDriver) ChunkById(tableName string, query map[string]interface{}, size int64, fn func([]Row) error) error {
+	return db.ChunkByIdContext(context.Background(), tableName, query, size, fn)
+}
+
+func (db *PostgresDriver) ChunkByIdContext(ctx context.Context, tableName string, query map[string]interface{}, size int64, fn func([]Row) error) error {
+	ctx = withOperation(ctx, "ChunkById", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return err
+	}
+	return walkById(db.queryWith(ctx), "postgres", "\""+tableName+"\"", where, size, fn)
+}
+
+func (db *PostgresDriver) BatchUpdate(tableName string, post map[string]interface{}, query map[string]interface{}, options *BatchOptions) (int64, error) {
+	return db.BatchUpdateContext(context.Background(), tableName, post, query, options)
+}
+
+func (db *PostgresDriver) BatchUpdateContext(ctx context.Context, tableName string, post map[string]interface{}, query map[string]interface{}, options *BatchOptions) (int64, error) {
+	ctx = withOperation(ctx, "BatchUpdate", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return 0, err
+	}
+	s, _ := GetUpdateSQL(tableName, post, map[string]interface{}{}, "postgres")
+	exec := func(s string, total int64, args ...interface{}) (int64, error) {
+		return execBatch(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected, total, args...)
+	}
+	return batchUpdateById(ctx, db.queryWith(UsePrimary(ctx)), exec, "postgres", "\""+tableName+"\"", where, s, options)
+}
+
+func (db *PostgresDriver) BatchDelete(tableName string, query map[string]interface{}, options *BatchOptions) (int64, error) {
+	return db.BatchDeleteContext(context.Background(), tableName, query, options)
+}
+
+func (db *PostgresDriver) BatchDeleteContext(ctx context.Context, tableName string, query map[string]interface{}, options *BatchOptions) (int64, error) {
+	ctx = withOperation(ctx, "BatchDelete", tableName)
+	where, err := WhereFromQuery(query)
+	if err = checkWhere(db.Safe, where, err); err != nil {
+		return 0, err
+	}
+	if where == "" {
+		return 0, nil
+	}
+	return batchLoop(ctx, options, func(size, total int64) (int64, bool, error) {
+		s := "delete from \"" + tableName + "\" where ctid in (select ctid from \"" + tableName + "\"" + where + " limit " + SqlQuote(size) + ")"
+		affected, err := execBatch(ctx, db.DB, db.beginTx, db.exec, s, db.MaxAffected, total)
+		return affected, affected < size, err
+	})
+}
+
+func (db *PostgresDriver) Sum(tableName string, column string, query map[string]interface{}) (float64, error) {
+	return db.SumContext(context.Background(), tableName, column, query)
+}
+
+func (db *PostgresDriver) SumContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (float64, error) {
+	ctx = withOperation(ctx, "Sum", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	return aggregateFloat(db.queryWith(ctx), "\""+tableName+"\"", "sum", column, where)
+}
+
+func (db *PostgresDriver) Avg(tableName string, column string, query map[string]interface{}) (float64, error) {
+	return db.AvgContext(context.Background(), tableName, column, query)
+}
+
+func (db *PostgresDriver) AvgContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (float64, error) {
+	ctx = withOperation(ctx, "Avg", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	return aggregateFloat(db.queryWith(ctx), "\""+tableName+"\"", "avg", column, where)
+}
+
+func (db *PostgresDriver) Min(tableName string, column string, query map[string]interface{}) (interface{}, error) {
+	return db.MinContext(context.Background(), tableName, column, query)
+}
+
+func (db *PostgresDriver) MinContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (interface{}, error) {
+	ctx = withOperation(ctx, "Min", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateValue(db.queryWith(ctx), "\""+tableName+"\"", "min", column, where)
+}
+
+func (db *PostgresDriver) Max(tableName string, column string, query map[string]interface{}) (interface{}, error) {
+	return db.MaxContext(context.Background(), tableName, column, query)
+}
+
+func (db *PostgresDriver) MaxContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (interface{}, error) {
+	ctx = withOperation(ctx, "Max", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateValue(db.queryWith(ctx), "\""+tableName+"\"", "max", column, where)
+}
+
+func (db *PostgresDriver) CountDistinct(tableName string, column string, query map[string]interface{}) (int64, error) {
+	return db.CountDistinctContext(context.Background(), tableName, column, query)
+}
+
+func (db *PostgresDriver) CountDistinctContext(ctx context.Context, tableName string, column string, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "CountDistinct", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return 0, err
+	}
+	return countDistinct(db.queryWith(ctx), "\""+tableName+"\"", column, where)
+}
+
+func (db *PostgresDriver) Pluck(tableName string, column string, query map[string]interface{}, orderBy string) ([]interface{}, error) {
+	return db.PluckContext(context.Background(), tableName, column, query, orderBy)
+}
+
+func (db *PostgresDriver) PluckContext(ctx context.Context, tableName string, column string, query map[string]interface{}, orderBy string) ([]interface{}, error) {
+	ctx = withOperation(ctx, "Pluck", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return pluck(db.queryWith(ctx), "\""+tableName+"\"", column, where, orderBy)
+}
+
+func (db *PostgresDriver) GroupBy(tableName string, columns []string, aggregates []Aggregate, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.GroupByContext(context.Background(), tableName, columns, aggregates, query)
+}
+
+func (db *PostgresDriver) GroupByContext(ctx context.Context, tableName string, columns []string, aggregates []Aggregate, query map[string]interface{}) ([]map[string]interface{}, error) {
+	ctx = withOperation(ctx, "GroupBy", tableName)
+	where, err := WhereFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return groupBy(db.queryWith(ctx), "\""+tableName+"\"", columns, aggregates, where)
+}
+
+func (db *PostgresDriver) GroupCount(tableName string, column string, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.GroupCountContext(context.Background(), tableName, column, query)
+}
+
+func (db *PostgresDriver) GroupCountContext(ctx context.Context, tableName string, column string, query map[string]interface{}) ([]map[string]interface{}, error) {
+	return db.GroupByContext(ctx, tableName, []string{column}, []Aggregate{{Func: "count", As: "count"}}, query)
+}
+
+func (db *PostgresDriver) FindOneJoin(sel *Select, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	return db.FindOneJoinContext(context.Background(), sel, query, orderBy)
+}
+
+func (db *PostgresDriver) FindOneJoinContext(ctx context.Context, sel *Select, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "FindOneJoin", sel.Table)
+	s, err := GetSelectSQL(sel, query, "postgres")
+	if err != nil {
+		return nil, err
+	}
+	if CheckOrderBy(orderBy) {
+		s += " order by " + orderBy
+	}
+	return db.QueryContext(ctx, s+" limit 1")
+}
+
+func (db *PostgresDriver) GetListJoin(sel *Select, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	return db.GetListJoinContext(context.Background(), sel, query, orderBy)
+}
+
+func (db *PostgresDriver) GetListJoinContext(ctx context.Context, sel *Select, query map[string]interface{}, orderBy string) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "GetListJoin", sel.Table)
+	s, err := GetSelectSQL(sel, query, "postgres")
+	if err != nil {
+		return nil, err
+	}
+	if CheckOrderBy(orderBy) {
+		s += " order by " + orderBy
+	}
+	return db.QueryContext(ctx, s)
+}
+
+func (db *PostgresDriver) CountJoin(sel *Select, query map[string]interface{}) (int64, error) {
+	return db.CountJoinContext(context.Background(), sel, query)
+}
+
+func (db *PostgresDriver) CountJoinContext(ctx context.Context, sel *Select, query map[string]interface{}) (int64, error) {
+	ctx = withOperation(ctx, "CountJoin", sel.Table)
+	var count int64
+	s, err := GetSelectCountSQL(sel, query, "postgres")
+	if err != nil {
+		return 0, err
+	}
+	err = queryScalar(db.queryWith(ctx), s, &count)
+	return count, err
+}
+
+func (db *PostgresDriver) GetPageJoin(sel *Select, query map[string]interface{}, orderBy string, page, size int64) (*sql.Rows, *Page, error) {
+	return db.GetPageJoinContext(context.Background(), sel, query, orderBy, page, size)
+}
+
+func (db *PostgresDriver) GetPageJoinContext(ctx context.Context, sel *Select, query map[string]interface{}, orderBy string, page, size int64) (*sql.Rows, *Page, error) {
+	ctx = withOperation(ctx, "GetPageJoin", sel.Table)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	total, err := db.CountJoinContext(ctx, sel, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := NewPage(page, size, total)
+	s, err := GetSelectSQL(sel, query, "postgres")
+	if err != nil {
+		return nil, nil, err
+	}
+	if CheckOrderBy(orderBy) {
+		s += " order by " + orderBy
+	}
+	rows, err := db.QueryContext(ctx, s+" limit $1 offset $2", p.Size, (p.Page-1)*p.Size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, p, nil
+}
+
+func (db *PostgresDriver) Relate(tableName string, relation Relation) error {
+	relations, err := addRelation(db.Relations, tableName, relation)
+	if err != nil {
+		return err
+	}
+	db.Relations = relations
+	return nil
+}
+
+func (db *PostgresDriver) GetListPreload(tableName string, query map[string]interface{}, orderBy string, with ...string) ([]map[string]interface{}, error) {
+	return db.GetListPreloadContext(context.Background(), tableName, query, orderBy, with...)
+}
+
+func (db *PostgresDriver) GetListPreloadContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, with ...string) ([]map[string]interface{}, error) {
+	ctx = withOperation(ctx, "GetListPreload", tableName)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	rows, err := db.GetListContext(ctx, tableName, query, orderBy)
+	if err != nil {
+		return nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, err
+	}
+	return list, preload(db.queryWith(ctx), "postgres", db.Relations, tableName, list, with)
+}
+
+func (db *PostgresDriver) GetPagePreload(tableName string, query map[string]interface{}, orderBy string, page, size int64, with ...string) ([]map[string]interface{}, *Page, error) {
+	return db.GetPagePreloadContext(context.Background(), tableName, query, orderBy, page, size, with...)
+}
+
+func (db *PostgresDriver) GetPagePreloadContext(ctx context.Context, tableName string, query map[string]interface{}, orderBy string, page, size int64, with ...string) ([]map[string]interface{}, *Page, error) {
+	ctx = withOperation(ctx, "GetPagePreload", tableName)
+	ctx = pinReplica(ctx, db.DB, db.Replicas, db.Balancer)
+	rows, p, err := db.GetPageContext(ctx, tableName, query, orderBy, page, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := ReturnListFromResults(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return list, p, preload(db.queryWith(ctx), "postgres", db.Relations, tableName, list, with)
+}
+
+// Increment adds by to column in a single update, so concurrent callers do not race.
+func (db *PostgresDriver) Increment(tableName string, column string, by float64, query map[string]interface{}) (int64, error) {
+	return db.IncrementContext(context.Background(), tableName, column, by, query)
+}
+
+func (db *PostgresDriver) IncrementContext(ctx context.Context, tableName string, column string, by float64, query map[string]interface{}) (int64, error) {
+	expr, err := incrementExpr(column, by)
+	if err != nil {
+		return 0, err
+	}
+	return db.UpdateContext(ctx, tableName, map[string]interface{}{column: expr}, query)
+}
+
+func (db *PostgresDriver) Decrement(tableName string, column string, by float64, query map[string]interface{}) (int64, error) {
+	return db.DecrementContext(context.Background(), tableName, column, by, query)
+}
+
+func (db *PostgresDriver) DecrementContext(ctx context.Context, tableName string, column string, by float64, query map[string]interface{}) (int64, error) {
+	return db.IncrementContext(ctx, tableName, column, -by, query)
+}
+
+// QueryNamed runs query with :name placeholders bound from a map or struct.
+func (db *PostgresDriver) QueryNamed(query string, arg interface{}) (*sql.Rows, error) {
+	return db.QueryNamedContext(context.Background(), query, arg)
+}
+
+func (db *PostgresDriver) QueryNamedContext(ctx context.Context, query string, arg interface{}) (*sql.Rows, error) {
+	ctx = withOperation(ctx, "QueryNamed", "")
+	s, args, err := CompileNamed(query, "postgres", arg)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, s, args...)
+}
+
+func (db *PostgresDriver) ExecNamed(query string, arg interface{}) (sql.Result, error) {
+	return db.ExecNamedContext(context.Background(), query, arg)
+}
+
+func (db *PostgresDriver) ExecNamedContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	ctx = withOperation(ctx, "ExecNamed", "")
+	s, args, err := CompileNamed(query, "postgres", arg)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, s, args...)
+}
+
+// Health reports latency, server version, pool stats, whether the server is a
+// standby in recovery and whether transactions default to read only.
+func (db *PostgresDriver) Health(ctx context.Context) *HealthStatus {
+	status := newHealthStatus(db.DriverName, db.DB.Stats(), db.Breaker)
+	return checkHealth(ctx, db.DB, status, func(ctx context.Context, status *HealthStatus) error {
+		var inRecovery bool
+		var readOnly string
+		err := db.DB.QueryRowContext(ctx, "select version(), pg_is_in_recovery(), current_setting('default_transaction_read_only')").Scan(&status.ServerVersion, &inRecovery, &readOnly)
+		if err != nil {
+			return err
+		}
+		status.ReadOnly = inRecovery || readOnly == "on"
+		status.Role = "primary"
+		if inRecovery {
+			status.Role = "replica"
+		}
+		return nil
+	})
+}
+
+// AddReplica opens a read replica pool. Plain selects through Query and the map based
+// readers then go to the replicas, picked by Balancer; writes and transactions stay
+// on the primary. Pass a ctx from UsePrimary to QueryContext or an XContext method
+// to read from the primary. Paged and preloading reads keep all their statements
+// on one replica.
+func (db *PostgresDriver) AddReplica(host string, port int, user, password, dbname string) error {
+	dataSourceName := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+	replica, err := openPool(db.DriverName, dataSourceName)
+	if err != nil {
+		return err
+	}
+	db.Replicas = append(db.Replicas, replica)
+	name := poolName(db.DriverName, dataSourceName)
+	db.replicaNames = append(db.replicaNames, name)
+	if db.Metrics != nil {
+		db.Metrics.RegisterPool(name, replica.Stats)
+	}
+	if db.Balancer == nil {
+		db.Balancer = &RoundRobin{}
+	}
+	return nil
+}
+
+func (db *PostgresDriver) SetBalancer(balancer Balancer) error {
+	db.Balancer = balancer
+	return nil
 }
@@ -0,0 +1,142 @@
+package DBDriver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	HasOne    = "has_one"
+	HasMany   = "has_many"
+	BelongsTo = "belongs_to"
+)
+
+// preloadBatch bounds the keys bound in one in (...) list, below every driver's
+// placeholder limit.
+const preloadBatch = 1000
+
+// Relation links rows of a table to rows of Table where row[LocalKey] equals related[ForeignKey].
+// For BelongsTo ForeignKey defaults to id, for HasOne and HasMany LocalKey defaults to id.
+type Relation struct {
+	Name       string
+	Kind       string
+	Table      string
+	LocalKey   string
+	ForeignKey string
+}
+
+func (r Relation) normalize() (Relation, error) {
+	switch r.Kind {
+	case BelongsTo:
+		if r.ForeignKey == "" {
+			r.ForeignKey = "id"
+		}
+	case HasOne, HasMany:
+		if r.LocalKey == "" {
+			r.LocalKey = "id"
+		}
+	default:
+		return r, errors.New("DBDriver: unsupported relation kind " + r.Kind)
+	}
+	if r.Name == "" {
+		r.Name = r.Table
+	}
+	for _, name := range []string{r.Table, r.LocalKey, r.ForeignKey} {
+		if err := checkColumn(name); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+func addRelation(relations map[string][]Relation, tableName string, relation Relation) (map[string][]Relation, error) {
+	relation, err := relation.normalize()
+	if err != nil {
+		return relations, err
+	}
+	if relations == nil {
+		relations = map[string][]Relation{}
+	}
+	list := relations[tableName]
+	for i, r := range list {
+		if r.Name == relation.Name {
+			list[i] = relation
+			return relations, nil
+		}
+	}
+	relations[tableName] = append(list, relation)
+	return relations, nil
+}
+
+func findRelation(relations map[string][]Relation, tableName, name string) (Relation, error) {
+	for _, r := range relations[tableName] {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return Relation{}, errors.New("DBDriver: no relation " + name + " on " + tableName)
+}
+
+// preload fetches every named relation with one in (...) query per 1000 keys, the keys
+// bound as arguments, and attaches the related row (has one, belongs to) or rows
+// (has many) to each row under the relation name.
+func preload(query func(string, ...interface{}) (*sql.Rows, error), driverName string, relations map[string][]Relation, tableName string, list []map[string]interface{}, names []string) error {
+	for _, name := range names {
+		relation, err := findRelation(relations, tableName, name)
+		if err != nil {
+			return err
+		}
+		seen := map[string]bool{}
+		values := make([]interface{}, 0, len(list))
+		for _, row := range list {
+			v, ok := row[relation.LocalKey]
+			if !ok || v == nil {
+				continue
+			}
+			key := fmt.Sprint(v)
+			if !seen[key] {
+				seen[key] = true
+				values = append(values, v)
+			}
+		}
+
+		related := map[string][]map[string]interface{}{}
+		for start := 0; start < len(values); start += preloadBatch {
+			batch := values[start:min(start+preloadBatch, len(values))]
+			marks := make([]string, len(batch))
+			for i := range batch {
+				marks[i] = placeholder(driverName, i+1)
+			}
+			s := "select * from " + quoteTable(relation.Table, driverName) + " where " + relation.ForeignKey + " in (" + strings.Join(marks, ", ") + ")"
+			rows, err := query(s, batch...)
+			if err != nil {
+				return err
+			}
+			err = Each(rows, func(row Row) error {
+				key := fmt.Sprint(row[relation.ForeignKey])
+				related[key] = append(related[key], row)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, row := range list {
+			items := related[fmt.Sprint(row[relation.LocalKey])]
+			if relation.Kind == HasMany {
+				if items == nil {
+					items = []map[string]interface{}{}
+				}
+				row[relation.Name] = items
+			} else if len(items) > 0 {
+				row[relation.Name] = items[0]
+			} else {
+				row[relation.Name] = nil
+			}
+		}
+	}
+	return nil
+}
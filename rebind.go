@@ -0,0 +1,45 @@
+package DBDriver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// RebindSQL rewrites ? placeholders to $n for Postgres, skipping literals, comments
+// and dollar quoted strings. The jsonb operators ?| and ?& are kept, and ?? is
+// written as a single ? so the jsonb ? operator stays usable. Other drivers get query back.
+func RebindSQL(query string, driverName string) string {
+	if driverName != "postgres" || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, segment := range segmentSQL(query, driverName) {
+		if !segment.code {
+			b.WriteString(segment.text)
+			continue
+		}
+		text := segment.text
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+			if c != '?' {
+				b.WriteByte(c)
+				continue
+			}
+			if i+1 < len(text) {
+				switch text[i+1] {
+				case '?':
+					b.WriteByte('?')
+					i++
+					continue
+				case '|', '&':
+					b.WriteByte(c)
+					continue
+				}
+			}
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+		}
+	}
+	return b.String()
+}
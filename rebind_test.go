@@ -0,0 +1,28 @@
+package DBDriver
+
+import "testing"
+
+func TestRebindSQL(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		driverName string
+		want       string
+	}{
+		{"mysql untouched", "select ? from t where a = ?", "mysql", "select ? from t where a = ?"},
+		{"numbered", "select ? from t where a = ?", "postgres", "select $1 from t where a = $2"},
+		{"literal", "select '?', ?", "postgres", "select '?', $1"},
+		{"escape string", `select E'it\'s ?', ?`, "postgres", `select E'it\'s ?', $1`},
+		{"comments", "select ? -- ?\n/* ? */ , ?", "postgres", "select $1 -- ?\n/* ? */ , $2"},
+		{"dollar quoted", "select $$?$$, ?", "postgres", "select $$?$$, $1"},
+		{"jsonb operators", "a ?| b and a ?& c and a ?? 'k' and d = ?", "postgres", "a ?| b and a ?& c and a ? 'k' and d = $1"},
+		{"quoted identifier", `select "a?" from t where b = ?`, "postgres", `select "a?" from t where b = $1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RebindSQL(tt.query, tt.driverName); got != tt.want {
+				t.Errorf("RebindSQL(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// Balancer picks the replica pool a read goes to.
+type Balancer interface {
+	Pick(replicas []*sql.DB) *sql.DB
+}
+
+type RoundRobin struct {
+	next uint64
+}
+
+func (b *RoundRobin) Pick(replicas []*sql.DB) *sql.DB {
+	n := atomic.AddUint64(&b.next, 1)
+	return replicas[(n-1)%uint64(len(replicas))]
+}
+
+// LeastConnections picks the replica with the fewest connections in use.
+type LeastConnections struct{}
+
+func (LeastConnections) Pick(replicas []*sql.DB) *sql.DB {
+	best := replicas[0]
+	inUse := best.Stats().InUse
+	for _, replica := range replicas[1:] {
+		if n := replica.Stats().InUse; n < inUse {
+			best, inUse = replica, n
+		}
+	}
+	return best
+}
+
+type primaryKey struct{}
+
+// UsePrimary sends reads made with ctx to the primary, e.g. to read your own writes.
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+type replicaKey struct{}
+
+// pinReplica picks the replica every read made with the returned ctx goes to, so
+// statements that must agree, like a page and its total, see the same data.
+func pinReplica(ctx context.Context, primary *sql.DB, replicas []*sql.DB, balancer Balancer) context.Context {
+	if len(replicas) == 0 || usePrimary(ctx) {
+		return ctx
+	}
+	if _, ok := ctx.Value(replicaKey{}).(*sql.DB); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, replicaKey{}, readConn(ctx, primary, replicas, balancer, "select"))
+}
+
+// readConn returns the pool for query: a replica for plain reads, the primary for
+// everything else, when ctx asks for it or when there are no replicas.
+func readConn(ctx context.Context, primary *sql.DB, replicas []*sql.DB, balancer Balancer, query string) *sql.DB {
+	if len(replicas) == 0 || usePrimary(ctx) || !isReadStatement(query) {
+		return primary
+	}
+	if pinned, ok := ctx.Value(replicaKey{}).(*sql.DB); ok {
+		return pinned
+	}
+	if balancer == nil {
+		return replicas[0]
+	}
+	return balancer.Pick(replicas)
+}
+
+// openPool opens a pool with the same settings Open uses for the primary.
+func openPool(driverName, dataSourceName string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	db.SetMaxOpenConns(20)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(time.Second * 10)
+	return db, nil
+}
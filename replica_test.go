@@ -0,0 +1,43 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestReadConn(t *testing.T) {
+	primary, replicas := &sql.DB{}, []*sql.DB{{}, {}}
+	balancer := &RoundRobin{}
+	ctx := context.Background()
+
+	if readConn(ctx, primary, replicas, balancer, "update article set title = 'a'") != primary {
+		t.Error("write went to a replica")
+	}
+	if readConn(UsePrimary(ctx), primary, replicas, balancer, "select 1") != primary {
+		t.Error("UsePrimary read went to a replica")
+	}
+	if readConn(ctx, primary, replicas, balancer, "select 1") == readConn(ctx, primary, replicas, balancer, "select 1") {
+		t.Error("round robin picked the same replica twice")
+	}
+
+	pinned := pinReplica(ctx, primary, replicas, balancer)
+	first := readConn(pinned, primary, replicas, balancer, "select count(1) from article")
+	if first == primary {
+		t.Fatal("pinned read went to the primary")
+	}
+	for i := 0; i < 3; i++ {
+		if readConn(pinned, primary, replicas, balancer, "select * from article") != first {
+			t.Fatal("pinned reads went to different replicas")
+		}
+	}
+	if pinReplica(pinned, primary, replicas, balancer) != pinned {
+		t.Error("pinReplica re-pinned an already pinned ctx")
+	}
+	if readConn(pinned, primary, replicas, balancer, "delete from article") != primary {
+		t.Error("write on a pinned ctx went to a replica")
+	}
+	if readConn(pinReplica(UsePrimary(ctx), primary, replicas, balancer), primary, replicas, balancer, "select 1") != primary {
+		t.Error("UsePrimary lost to pinReplica")
+	}
+}
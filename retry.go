@@ -0,0 +1,136 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RetryPolicy retries transient failures with exponential backoff and jitter.
+// Reads outside transactions are retried automatically; writes only when their
+// context is marked with RetrySafe, and transactions only through RunInTx.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Jitter is the fraction of each delay that is randomised, from 0 to 1.
+	Jitter float64
+	// Retryable defaults to IsRetryable.
+	Retryable func(error) bool
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: 2 * time.Second, Jitter: 0.5}
+}
+
+// IsRetryable reports whether err is a connection failure, deadlock, lock timeout
+// or serialization failure.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrConnection) || errors.Is(err, ErrDeadlock) ||
+		errors.Is(err, ErrLockTimeout) || errors.Is(err, ErrSerialization)
+}
+
+type retrySafeKey struct{}
+
+// RetrySafe marks writes made with ctx as safe to repeat after a transient failure.
+func RetrySafe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retrySafeKey{}, true)
+}
+
+func isRetrySafe(ctx context.Context) bool {
+	safe, _ := ctx.Value(retrySafeKey{}).(bool)
+	return safe
+}
+
+// lockingReadRegexp matches the locking clauses of MySQL and Postgres selects.
+var lockingReadRegexp = regexp.MustCompile(`(?i)\bfor\s+(update|share|no\s+key\s+update|key\s+share)\b|\block\s+in\s+share\s+mode\b`)
+
+// isReadStatement reports whether query is a plain read that can be repeated. A
+// locking read is not: it belongs on the primary and must not be retried.
+func isReadStatement(query string) bool {
+	fields := strings.Fields(strings.ToLower(query))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "select":
+		return !lockingReadRegexp.MatchString(query)
+	case "show", "explain", "describe":
+		return true
+	}
+	return false
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		jitter := time.Duration(float64(delay) * p.Jitter * rand.Float64())
+		delay -= jitter
+	}
+	return delay
+}
+
+// do calls fn until it succeeds, fails with a non retryable error, the attempts
+// run out or ctx is done. A nil policy calls fn once.
+func (p *RetryPolicy) do(ctx context.Context, fn func() error) error {
+	if p == nil {
+		return fn()
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(err) {
+			return err
+		}
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func retryPolicyFor(p *RetryPolicy, ctx context.Context, conn sqlConn, read bool) *RetryPolicy {
+	if _, inTx := conn.(*sql.Tx); inTx {
+		return nil
+	}
+	if read || isRetrySafe(ctx) {
+		return p
+	}
+	return nil
+}
+
+// runInTx runs fn in a transaction begun by begin, committing on success. When ctx is
+// marked RetrySafe the whole transaction is repeated after a retryable failure.
+func runInTx(ctx context.Context, p *RetryPolicy, begin txBegin, fn func(*sql.Tx) error) error {
+	if !isRetrySafe(ctx) {
+		p = nil
+	}
+	return p.do(ctx, func() error {
+		tx, release, err := begin(ctx)
+		if err != nil {
+			return ClassifyError(err)
+		}
+		defer release()
+		if err = fn(tx); err != nil {
+			tx.Rollback()
+			return ClassifyError(err)
+		}
+		return ClassifyError(tx.Commit())
+	})
+}
@@ -0,0 +1,31 @@
+package DBDriver
+
+import "testing"
+
+func TestIsReadStatement(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"select * from article", true},
+		{"  SELECT id FROM article where id = 1", true},
+		{"show tables", true},
+		{"select * from article where id = 1 for update", false},
+		{"select * from article where id = 1 FOR UPDATE", false},
+		{"select * from article where id = 1\nfor\tupdate nowait", false},
+		{"select id from article for update;", false},
+		{"select * from article for share", false},
+		{"select * from article for no key update", false},
+		{"select * from article lock in share mode", false},
+		{"select * from article LOCK\n IN SHARE MODE", false},
+		{"select before_update from article", true},
+		{"select * from article where platform = 1", true},
+		{"update article set hits = 1", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isReadStatement(tt.query); got != tt.want {
+			t.Errorf("isReadStatement(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package DBDriver
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// MapToStruct copies a row returned by ReturnMapFromResult into the struct dest points to.
+// Columns are matched by the db tag, then the json tag, then the field name.
+func MapToStruct(row map[string]interface{}, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("DBDriver: dest must be a pointer to struct")
+	}
+	return setStruct(row, v.Elem())
+}
+
+// MapsToStructs fills the slice dest points to with one element per row.
+func MapsToStructs(list []map[string]interface{}, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.New("DBDriver: dest must be a pointer to slice")
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return errors.New("DBDriver: dest must be a pointer to slice of struct")
+	}
+	result := reflect.MakeSlice(slice.Type(), 0, len(list))
+	for _, row := range list {
+		item := reflect.New(elemType)
+		if err := setStruct(row, item.Elem()); err != nil {
+			return err
+		}
+		if isPtr {
+			result = reflect.Append(result, item)
+		} else {
+			result = reflect.Append(result, item.Elem())
+		}
+	}
+	slice.Set(result)
+	return nil
+}
+
+func columnName(field reflect.StructField) string {
+	if tag := field.Tag.Get("db"); tag != "" {
+		return strings.Split(tag, ",")[0]
+	}
+	if tag := field.Tag.Get("json"); tag != "" {
+		return strings.Split(tag, ",")[0]
+	}
+	return field.Name
+}
+
+func snakeCase(name string) string {
+	s := ""
+	for i, r := range name {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				s += "_"
+			}
+			r += 'a' - 'A'
+		}
+		s += string(r)
+	}
+	return s
+}
+
+func lookupColumn(row map[string]interface{}, field reflect.StructField) (interface{}, bool) {
+	name := columnName(field)
+	if name == "-" {
+		return nil, false
+	}
+	if v, ok := row[name]; ok {
+		return v, true
+	}
+	if v, ok := row[strings.ToLower(name)]; ok {
+		return v, true
+	}
+	v, ok := row[snakeCase(name)]
+	return v, ok
+}
+
+func setStruct(row map[string]interface{}, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		value, ok := lookupColumn(row, field)
+		if !ok || value == nil {
+			continue
+		}
+		if err := setValue(v.Field(i), value); err != nil {
+			return fmt.Errorf("DBDriver: field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(field reflect.Value, value interface{}) error {
+	if field.Kind() == reflect.Ptr {
+		item := reflect.New(field.Type().Elem())
+		if err := setValue(item.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(item)
+		return nil
+	}
+	if row, ok := value.(map[string]interface{}); ok && field.Kind() == reflect.Struct {
+		return setStruct(row, field)
+	}
+	if list, ok := value.([]map[string]interface{}); ok && field.Kind() == reflect.Slice {
+		return MapsToStructs(list, field.Addr().Interface())
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Type().AssignableTo(field.Type()) {
+		field.Set(rv)
+		return nil
+	}
+	s, isString := value.(string)
+	if field.Type() == reflect.TypeOf(time.Time{}) && isString {
+		for _, layout := range timeLayouts {
+			if tm, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				field.Set(reflect.ValueOf(tm))
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot parse time %q", s)
+	}
+	if isString {
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			if field.OverflowInt(n) {
+				return fmt.Errorf("%s overflows %s", s, field.Type())
+			}
+			field.SetInt(n)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			if field.OverflowUint(n) {
+				return fmt.Errorf("%s overflows %s", s, field.Type())
+			}
+			field.SetUint(n)
+			return nil
+		case reflect.Float32, reflect.Float64:
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return err
+			}
+			field.SetFloat(n)
+			return nil
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return err
+			}
+			field.SetBool(b)
+			return nil
+		}
+	}
+	if field.Kind() == reflect.Bool {
+		switch {
+		case isIntKind(rv.Kind()):
+			field.SetBool(rv.Int() != 0)
+			return nil
+		case isUintKind(rv.Kind()):
+			field.SetBool(rv.Uint() != 0)
+			return nil
+		}
+	}
+	if rv.Type().ConvertibleTo(field.Type()) {
+		if err := checkConversion(rv, field); err != nil {
+			return err
+		}
+		field.Set(rv.Convert(field.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %T to %s", value, field.Type())
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// checkConversion rejects the conversions reflect allows that change the value:
+// integers to strings, which yield the rune, fractional floats to integers and
+// numbers that overflow field.
+func checkConversion(rv reflect.Value, field reflect.Value) error {
+	from := rv.Kind()
+	to := field.Kind()
+	if (isIntKind(from) || isUintKind(from)) && to == reflect.String {
+		return fmt.Errorf("cannot assign %s to %s", rv.Type(), field.Type())
+	}
+	if isFloatKind(from) && (isIntKind(to) || isUintKind(to)) {
+		f := rv.Float()
+		if f != math.Trunc(f) {
+			return fmt.Errorf("%v is not an integer", f)
+		}
+		if isIntKind(to) && (f < math.MinInt64 || f >= math.MaxInt64) || isUintKind(to) && (f < 0 || f >= math.MaxUint64) {
+			return fmt.Errorf("%v overflows %s", f, field.Type())
+		}
+	}
+	switch {
+	case isIntKind(to) && isIntKind(from):
+		if field.OverflowInt(rv.Int()) {
+			return fmt.Errorf("%v overflows %s", rv.Int(), field.Type())
+		}
+	case isIntKind(to) && isUintKind(from):
+		if rv.Uint() > math.MaxInt64 || field.OverflowInt(int64(rv.Uint())) {
+			return fmt.Errorf("%v overflows %s", rv.Uint(), field.Type())
+		}
+	case isIntKind(to) && isFloatKind(from):
+		if field.OverflowInt(int64(rv.Float())) {
+			return fmt.Errorf("%v overflows %s", rv.Float(), field.Type())
+		}
+	case isUintKind(to) && isIntKind(from):
+		if rv.Int() < 0 || field.OverflowUint(uint64(rv.Int())) {
+			return fmt.Errorf("%v overflows %s", rv.Int(), field.Type())
+		}
+	case isUintKind(to) && isUintKind(from):
+		if field.OverflowUint(rv.Uint()) {
+			return fmt.Errorf("%v overflows %s", rv.Uint(), field.Type())
+		}
+	case isUintKind(to) && isFloatKind(from):
+		if field.OverflowUint(uint64(rv.Float())) {
+			return fmt.Errorf("%v overflows %s", rv.Float(), field.Type())
+		}
+	}
+	return nil
+}
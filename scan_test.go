@@ -0,0 +1,28 @@
+package DBDriver
+
+import "testing"
+
+func TestMapToStructBool(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  bool
+	}{
+		{int64(1), true},
+		{int64(0), false},
+		{int8(1), true},
+		{uint8(1), true},
+		{"1", true},
+		{"false", false},
+		{true, true},
+	}
+	for _, tt := range tests {
+		var dest struct{ Published bool }
+		if err := MapToStruct(map[string]interface{}{"published": tt.value}, &dest); err != nil {
+			t.Errorf("MapToStruct(%T(%v)): %v", tt.value, tt.value, err)
+			continue
+		}
+		if dest.Published != tt.want {
+			t.Errorf("MapToStruct(%T(%v)) = %v, want %v", tt.value, tt.value, dest.Published, tt.want)
+		}
+	}
+}
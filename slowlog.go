@@ -0,0 +1,206 @@
+package DBDriver
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxFingerprints = 1000
+	slowLogSamples         = 512
+)
+
+var (
+	placeholderListRegexp = regexp.MustCompile(`\?( *, *\?)+`)
+	inListRegexp          = regexp.MustCompile(` in \(\?\)`)
+)
+
+type SlowQuery struct {
+	SQL         string
+	Fingerprint string
+	Duration    time.Duration
+	Err         error
+}
+
+type QueryStats struct {
+	Fingerprint string        `json:"fingerprint"`
+	Count       int64         `json:"count"`
+	Slow        int64         `json:"slow"`
+	Total       time.Duration `json:"total"`
+	Avg         time.Duration `json:"avg"`
+	Max         time.Duration `json:"max"`
+	P99         time.Duration `json:"p99"`
+}
+
+type queryStat struct {
+	stats   QueryStats
+	samples []time.Duration
+	next    int
+}
+
+// SlowLog keeps per fingerprint timing statistics for every statement and reports
+// statements slower than Threshold to Logger at warn level and to OnSlow.
+// Without a Logger the driver's logger is used.
+type SlowLog struct {
+	Threshold time.Duration
+	Logger    *slog.Logger
+	OnSlow    func(SlowQuery)
+	// MaxFingerprints bounds memory; statements with new fingerprints are not
+	// tracked once it is reached. Defaults to 1000.
+	MaxFingerprints int
+
+	mu    sync.Mutex
+	stats map[string]*queryStat
+}
+
+func NewSlowLog(threshold time.Duration) *SlowLog {
+	return &SlowLog{Threshold: threshold}
+}
+
+// observe records one statement; with redact the statement is reported as its
+// fingerprint, keeping inlined values out of the log and OnSlow.
+func (l *SlowLog) observe(ctx context.Context, logger *slog.Logger, query string, redact bool, duration time.Duration, err error) {
+	if l == nil {
+		return
+	}
+	fingerprint := Fingerprint(query)
+	if redact {
+		query = fingerprint
+	}
+	slow := l.Threshold > 0 && duration >= l.Threshold
+
+	l.mu.Lock()
+	if l.stats == nil {
+		l.stats = map[string]*queryStat{}
+	}
+	stat, ok := l.stats[fingerprint]
+	max := l.MaxFingerprints
+	if max <= 0 {
+		max = defaultMaxFingerprints
+	}
+	if !ok && len(l.stats) < max {
+		stat = &queryStat{stats: QueryStats{Fingerprint: fingerprint}, samples: make([]time.Duration, 0, 16)}
+		l.stats[fingerprint] = stat
+	}
+	if stat != nil {
+		stat.stats.Count++
+		stat.stats.Total += duration
+		if duration > stat.stats.Max {
+			stat.stats.Max = duration
+		}
+		if slow {
+			stat.stats.Slow++
+		}
+		if len(stat.samples) < slowLogSamples {
+			stat.samples = append(stat.samples, duration)
+		} else {
+			stat.samples[stat.next] = duration
+			stat.next = (stat.next + 1) % slowLogSamples
+		}
+	}
+	l.mu.Unlock()
+
+	if !slow {
+		return
+	}
+	if l.Logger != nil {
+		logger = l.Logger
+	}
+	if logger != nil {
+		attrs := []slog.Attr{
+			slog.String("sql", query),
+			slog.String("fingerprint", fingerprint),
+			slog.Duration("duration", duration),
+			slog.Duration("threshold", l.Threshold),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		logger.LogAttrs(ctx, slog.LevelWarn, "slow sql", attrs...)
+	}
+	if l.OnSlow != nil {
+		l.OnSlow(SlowQuery{SQL: query, Fingerprint: fingerprint, Duration: duration, Err: err})
+	}
+}
+
+// Top returns the statistics of the n fingerprints with the highest total time,
+// or all of them when n <= 0. p99 is computed over the latest samples.
+func (l *SlowLog) Top(n int) []QueryStats {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	list := make([]QueryStats, 0, len(l.stats))
+	for _, stat := range l.stats {
+		s := stat.stats
+		if s.Count > 0 {
+			s.Avg = s.Total / time.Duration(s.Count)
+		}
+		samples := append([]time.Duration(nil), stat.samples...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		if len(samples) > 0 {
+			s.P99 = samples[(len(samples)*99-1)/100]
+		}
+		list = append(list, s)
+	}
+	l.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Total > list[j].Total })
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+func (l *SlowLog) Reset() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.stats = nil
+	l.mu.Unlock()
+}
+
+// Fingerprint normalises a statement so that calls differing only in literal values
+// group together: literals and placeholders become ?, in lists collapse to (?+),
+// comments are dropped and whitespace and case are folded.
+func Fingerprint(query string) string {
+	var b strings.Builder
+	for _, segment := range segmentSQL(query, "") {
+		text := segment.text
+		if !segment.code {
+			switch text[0] {
+			case '\'', 'E', 'e':
+				b.WriteString("?")
+			case '-', '/', '#':
+				b.WriteString(" ")
+			case '$':
+				b.WriteString("?")
+			default:
+				b.WriteString(text)
+			}
+			continue
+		}
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+			prevIdent := i > 0 && (isLetter(text[i-1]) || isDigit(text[i-1]) || text[i-1] == '_')
+			if (isDigit(c) && !prevIdent) || (c == '$' && i+1 < len(text) && isDigit(text[i+1])) {
+				j := i + 1
+				for j < len(text) && (isDigit(text[j]) || text[j] == '.') {
+					j++
+				}
+				b.WriteString("?")
+				i = j - 1
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	s := strings.ToLower(strings.Join(strings.Fields(b.String()), " "))
+	s = placeholderListRegexp.ReplaceAllString(s, "?")
+	return inListRegexp.ReplaceAllString(s, " in (?+)")
+}
@@ -0,0 +1,29 @@
+package DBDriver
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"literals", "SELECT * FROM t WHERE a = 'x' AND b = 12.5", "select * from t where a = ? and b = ?"},
+		{"placeholders", "select * from t where a = $1 and b = ?", "select * from t where a = ? and b = ?"},
+		{"identifiers with digits", "select col1 from t2", "select col1 from t2"},
+		{"escape string", `select E'it\'s secret' from t`, "select ? from t"},
+		{"dollar quoted", "select $$secret$$", "select ?"},
+		{"comments", "select 1 -- note\n/* more */ from t", "select ? from t"},
+		{"whitespace", "select\n\t*   from  t", "select * from t"},
+		{"in list", "select * from t where id in (1, 2, 3)", "select * from t where id in (?+)"},
+		{"placeholder list", "select * from t where id IN ($1, $2)", "select * from t where id in (?+)"},
+		{"quoted identifier", `select "Name" from t`, `select "name" from t`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Fingerprint(tt.query); got != tt.want {
+				t.Errorf("Fingerprint(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package DBDriver
+
+import (
+	"database/sql"
+	"errors"
+	"iter"
+)
+
+type Row = map[string]interface{}
+
+// ErrStop can be returned from an Each callback to stop iterating without an error.
+var ErrStop = errors.New("DBDriver: stop iteration")
+
+type rowScanner struct {
+	columns  []string
+	values   []interface{}
+	scanArgs []interface{}
+}
+
+func newRowScanner(rows *sql.Rows) (*rowScanner, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	s := &rowScanner{
+		columns:  columns,
+		values:   make([]interface{}, len(columns)),
+		scanArgs: make([]interface{}, len(columns)),
+	}
+	for i := range s.values {
+		s.scanArgs[i] = &s.values[i]
+	}
+	return s, nil
+}
+
+func (s *rowScanner) scan(rows *sql.Rows) (Row, error) {
+	if err := rows.Scan(s.scanArgs...); err != nil {
+		return nil, err
+	}
+	row := make(Row, len(s.columns))
+	for i, col := range s.values {
+		c, ok := col.([]uint8)
+		if ok {
+			col = string(c)
+		}
+		if col != nil {
+			row[s.columns[i]] = col
+		}
+	}
+	return row, nil
+}
+
+// Each calls fn for every row and closes rows when done, when fn returns an error
+// or when scanning fails. Returning ErrStop from fn ends the loop early with a nil error.
+func Each(rows *sql.Rows, fn func(Row) error) error {
+	defer rows.Close()
+	scanner, err := newRowScanner(rows)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		row, err := scanner.scan(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(row); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Iterate returns an iterator over rows for use with range. rows are closed
+// when the loop finishes or breaks; an error is yielded once as the last pair.
+//
+//	rows, err := db.GetList("article", query, "id asc")
+//	for row, err := range DBDriver.Iterate(rows) {
+//		...
+//	}
+func Iterate(rows *sql.Rows) iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		defer rows.Close()
+		scanner, err := newRowScanner(rows)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for rows.Next() {
+			row, err := scanner.scan(rows)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
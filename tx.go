@@ -0,0 +1,45 @@
+package DBDriver
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Tx is the transaction RunInTx hands to fn. Its statements go through the driver
+// like any other, so hooks, logging, the slow log, metrics, tracing and error
+// classification apply; they are never retried on their own, RunInTx retries the
+// whole transaction. With SetRebind, ? placeholders work here too.
+type Tx struct {
+	Tx *sql.Tx
+
+	driverName string
+	rebind     bool
+	query      func(context.Context, sqlConn, string, ...interface{}) (*sql.Rows, error)
+	exec       func(context.Context, sqlConn, string, ...interface{}) (sql.Result, error)
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if tx.rebind {
+		query = RebindSQL(query, tx.driverName)
+	}
+	return tx.query(ctx, tx.Tx, query, args...)
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if tx.rebind {
+		query = RebindSQL(query, tx.driverName)
+	}
+	return tx.exec(ctx, tx.Tx, query, args...)
+}
+
+// txBegin begins a transaction and returns a func that must be called once it has
+// committed or rolled back.
+type txBegin func(context.Context) (*sql.Tx, func(), error)
@@ -0,0 +1,62 @@
+package DBDriver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunInTx(t *testing.T) {
+	db := newStubDriver(t)
+	limiter := NewLimiter(1, 0, 0)
+	db.SetLimiter(limiter)
+	var events []QueryEvent
+	db.AddHook(HookFuncs{AfterFunc: func(ctx context.Context, event *QueryEvent) {
+		events = append(events, *event)
+	}})
+
+	err := db.RunInTx(context.Background(), func(tx *Tx) error {
+		if _, err := tx.Exec("update article set title = ? where id = ?", "a", 1); err != nil {
+			return err
+		}
+		rows, err := tx.Query("select id from article where id = ?", 1)
+		if err != nil {
+			return err
+		}
+		rows.Close()
+		if _, err = db.Query("select id from article"); !errors.Is(err, ErrLimiterFull) {
+			t.Errorf("query outside the transaction = %v, want ErrLimiterFull", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiter.InFlight() != 0 {
+		t.Errorf("limiter still holds %d slots after commit", limiter.InFlight())
+	}
+	if len(events) != 3 {
+		t.Fatalf("hooks saw %d statements, want 3", len(events))
+	}
+	for _, event := range events[:2] {
+		if !event.InTx {
+			t.Errorf("%q ran outside the transaction", event.SQL)
+		}
+	}
+}
+
+func TestRunInTxBreaker(t *testing.T) {
+	db := newStubDriver(t)
+	breaker := NewCircuitBreaker(1, time.Hour)
+	breaker.allow()
+	breaker.done(&DBError{Kind: ErrConnection})
+	db.SetCircuitBreaker(breaker)
+	err := db.RunInTx(context.Background(), func(tx *Tx) error {
+		t.Fatal("fn ran with an open breaker")
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("RunInTx = %v, want ErrCircuitOpen", err)
+	}
+}
@@ -0,0 +1,211 @@
+package DBDriver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SubQuery selects Column from Table filtered by Query. As filter value it becomes
+// "key in (select ...)"; wrapped with Exists or NotExists it becomes an exists filter.
+// On correlates the sub query with the outer one, e.g. "p.article_id = a.id".
+type SubQuery struct {
+	Table  string
+	As     string
+	Column string
+	On     string
+	Query  map[string]interface{}
+}
+
+type ExistsCondition struct {
+	Sub *SubQuery
+	Not bool
+}
+
+func Exists(sub *SubQuery) ExistsCondition {
+	return ExistsCondition{Sub: sub}
+}
+
+func NotExists(sub *SubQuery) ExistsCondition {
+	return ExistsCondition{Sub: sub, Not: true}
+}
+
+// WhereBuilder builds a where clause and its bound arguments. Nested sub queries share
+// the builder, so Postgres $n placeholders keep counting across every part.
+// With Inline set, values are quoted into the statement instead of bound.
+type WhereBuilder struct {
+	DriverName string
+	Inline     bool
+	Args       []interface{}
+	// Offset is the number of placeholders already used before the where clause.
+	Offset int
+}
+
+func NewWhereBuilder(driverName string) *WhereBuilder {
+	return &WhereBuilder{DriverName: driverName}
+}
+
+func (b *WhereBuilder) bind(v interface{}) string {
+	if b.Inline {
+		return SqlQuote(v)
+	}
+	b.Args = append(b.Args, v)
+	if b.DriverName == "postgres" {
+		return "$" + strconv.Itoa(b.Offset+len(b.Args))
+	}
+	return "?"
+}
+
+// Where returns " where ..." for query, or an empty string when it has no conditions.
+// A key that cannot be rendered, also inside a sub query, gives " where 1 = 0" and
+// an error wrapping ErrInvalidFilter rather than a dropped condition.
+func (b *WhereBuilder) Where(query map[string]interface{}) (string, error) {
+	conditions, err := b.conditions(query)
+	if err != nil {
+		// like WhereFromQuery, fail closed for callers that drop the error
+		return " where 1 = 0", err
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " where " + strings.Join(conditions, " and "), nil
+}
+
+func (b *WhereBuilder) conditions(query map[string]interface{}) ([]string, error) {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	conditions := make([]string, 0, len(keys))
+	for _, k := range keys {
+		c, err := b.condition(k, query[k])
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+func (b *WhereBuilder) condition(k string, v interface{}) (string, error) {
+	switch value := v.(type) {
+	case *SubQuery:
+		sub, err := b.subQuery(value)
+		if err != nil {
+			return "", err
+		}
+		return k + " in (" + sub + ")", nil
+	case ExistsCondition:
+		sub, err := b.subQuery(value.Sub)
+		if err != nil {
+			return "", err
+		}
+		if value.Not {
+			return "not exists (" + sub + ")", nil
+		}
+		return "exists (" + sub + ")", nil
+	case Expr:
+		return k + " = " + value.SQL, nil
+	case map[string]interface{}:
+		return b.operator(k, value)
+	}
+	if IsSimpleType(v) {
+		return k + " = " + b.bind(v), nil
+	}
+	return "", fmt.Errorf("%w: unsupported value %T for %s", ErrInvalidFilter, v, k)
+}
+
+func (b *WhereBuilder) operator(k string, m map[string]interface{}) (string, error) {
+	o, _ := m["operater"].(string)
+	value, ok := m["value"]
+	if !ok {
+		return "", fmt.Errorf("%w: missing value for %s", ErrInvalidFilter, k)
+	}
+	switch strings.ToLower(o) {
+	case "=", "!=", "<>", ">", ">=", "<", "<=":
+		if e, ok := value.(Expr); ok {
+			return k + " " + o + " " + e.SQL, nil
+		}
+		if sub, ok := value.(*SubQuery); ok {
+			s, err := b.subQuery(sub)
+			if err != nil {
+				return "", err
+			}
+			return k + " " + o + " (" + s + ")", nil
+		}
+		if IsSimpleType(value) {
+			return k + " " + o + " " + b.bind(value), nil
+		}
+	case "like":
+		if s, ok := value.(string); ok {
+			return k + " like " + b.bind("%"+s+"%"), nil
+		}
+	case "in", "not in":
+		if sub, ok := value.(*SubQuery); ok {
+			s, err := b.subQuery(sub)
+			if err != nil {
+				return "", err
+			}
+			return k + " " + strings.ToLower(o) + " (" + s + ")", nil
+		}
+		if list, ok := value.([]interface{}); ok && len(list) > 0 {
+			parts := make([]string, len(list))
+			for i, item := range list {
+				parts[i] = b.bind(item)
+			}
+			return k + " " + strings.ToLower(o) + " (" + strings.Join(parts, ", ") + ")", nil
+		}
+	case "between":
+		if list, ok := value.([]interface{}); ok && len(list) == 2 {
+			return k + " between " + b.bind(list[0]) + " and " + b.bind(list[1]), nil
+		}
+	default:
+		return "", fmt.Errorf("%w: unsupported operator %q for %s", ErrInvalidFilter, o, k)
+	}
+	return "", fmt.Errorf("%w: unsupported value %T for %s %s", ErrInvalidFilter, value, k, o)
+}
+
+func (b *WhereBuilder) subQuery(sub *SubQuery) (string, error) {
+	if sub == nil {
+		return "", errors.New("DBDriver: nil sub query")
+	}
+	if err := checkColumn(sub.Table); err != nil {
+		return "", err
+	}
+	column := sub.Column
+	if column == "" {
+		column = "1"
+	} else if err := checkColumn(column); err != nil {
+		return "", err
+	}
+	from := sub.Table
+	if b.DriverName != "" {
+		from = quoteTable(sub.Table, b.DriverName)
+	}
+	if sub.As != "" {
+		if err := checkColumn(sub.As); err != nil {
+			return "", err
+		}
+		from += " as " + sub.As
+	}
+	conditions := make([]string, 0, 2)
+	if sub.On != "" {
+		if !joinOnRegexp.MatchString(strings.TrimSpace(sub.On)) {
+			return "", errors.New("DBDriver: invalid sub query condition " + sub.On)
+		}
+		conditions = append(conditions, strings.TrimSpace(sub.On))
+	}
+	nested, err := b.conditions(sub.Query)
+	if err != nil {
+		return "", err
+	}
+	conditions = append(conditions, nested...)
+	s := "select " + column + " from " + from
+	if len(conditions) > 0 {
+		s += " where " + strings.Join(conditions, " and ")
+	}
+	return s, nil
+}
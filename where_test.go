@@ -0,0 +1,68 @@
+package DBDriver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWhereBuilderPostgres(t *testing.T) {
+	tests := []struct {
+		name  string
+		query map[string]interface{}
+		want  string
+		args  []interface{}
+	}{
+		{
+			name:  "in list",
+			query: map[string]interface{}{"id": map[string]interface{}{"operater": "in", "value": []interface{}{1, 2}}, "status": 1},
+			want:  " where id in ($1, $2) and status = $3",
+			args:  []interface{}{1, 2, 1},
+		},
+		{
+			name: "not in sub query",
+			query: map[string]interface{}{
+				"author_id": map[string]interface{}{"operater": "not in", "value": &SubQuery{Table: "banned", Column: "user_id", Query: map[string]interface{}{"reason": "spam"}}},
+				"status":    1,
+			},
+			want: ` where author_id not in (select user_id from "banned" where reason = $1) and status = $2`,
+			args: []interface{}{"spam", 1},
+		},
+		{
+			name: "nested sub queries",
+			query: map[string]interface{}{
+				"a": 1,
+				"id": &SubQuery{Table: "comment", Column: "article_id", Query: map[string]interface{}{
+					"score": map[string]interface{}{"operater": ">", "value": 3},
+					"user_id": &SubQuery{Table: "user", Column: "id", Query: map[string]interface{}{
+						"level": map[string]interface{}{"operater": "<>", "value": 0},
+					}},
+				}},
+				"z": "x",
+			},
+			want: ` where a = $1 and id in (select article_id from "comment" where score > $2 and user_id in (select id from "user" where level <> $3)) and z = $4`,
+			args: []interface{}{1, 3, 0, "x"},
+		},
+		{
+			name: "exists with correlation",
+			query: map[string]interface{}{
+				"_": Exists(&SubQuery{Table: "post", As: "p", On: "p.article_id = a.id", Query: map[string]interface{}{"p.hidden": false}}),
+			},
+			want: ` where exists (select 1 from "post" as p where p.article_id = a.id and p.hidden = $1)`,
+			args: []interface{}{false},
+		},
+	}
+	for _, tt := range tests {
+		b := NewWhereBuilder("postgres")
+		got, err := b.Where(tt.query)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: Where = %q, want %q", tt.name, got, tt.want)
+		}
+		if !reflect.DeepEqual(b.Args, tt.args) {
+			t.Errorf("%s: Args = %v, want %v", tt.name, b.Args, tt.args)
+		}
+	}
+}